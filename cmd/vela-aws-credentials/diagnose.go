@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Cargill/vela-aws-credentials/pkg/plugin"
+	"github.com/urfave/cli/v2"
+)
+
+// diagnoseCommand explains likely AssumeRoleWithWebIdentity failures.
+var diagnoseCommand = &cli.Command{
+	Name:      "diagnose",
+	Usage:     "explain likely AssumeRoleWithWebIdentity failures for a role and ID token",
+	UsageText: "vela-aws-credentials diagnose --role <arn> (--token <jwt> | --claims <json|file>) [--policy <file> | --fetch]",
+	Action:    diagnose,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_ROLE", "AWS_CREDENTIALS_ROLE"},
+			Name:     "role",
+			Usage:    "AWS IAM role ARN that failed to be assumed",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "token",
+			Usage: "raw ID token to decode (the signature is not verified)",
+		},
+		&cli.StringFlag{
+			Name:  "claims",
+			Usage: "decoded ID token claims as JSON or a path to a JSON file",
+		},
+		&cli.StringFlag{
+			EnvVars: []string{"PARAMETER_AUDIENCE", "AWS_CREDENTIALS_AUDIENCE"},
+			Name:    "audience",
+			Usage:   "audience the plugin requested for the ID token",
+			Value:   "sts.amazonaws.com",
+		},
+		&cli.IntFlag{
+			EnvVars: []string{"PARAMETER_ROLE_DURATION_SECONDS", "AWS_CREDENTIALS_ROLE_DURATION_SECONDS"},
+			Name:    "duration",
+			Usage:   "role duration in seconds requested by the plugin",
+			Value:   3600,
+		},
+		&cli.IntFlag{
+			Name:  "max-session-duration",
+			Usage: "maximum session duration of the role in seconds, if known",
+		},
+		&cli.StringFlag{
+			Name:  "policy",
+			Usage: "path to the role's trust policy document",
+		},
+		&cli.BoolFlag{
+			Name:  "fetch",
+			Usage: "fetch the trust policy, maximum session duration and OIDC providers with the AWS credentials in the environment",
+		},
+		&cli.StringFlag{
+			EnvVars: []string{"PARAMETER_REGION", "AWS_CREDENTIALS_REGION"},
+			Name:    "region",
+			Usage:   "AWS region to use when fetching role details",
+			Value:   "us-east-1",
+		},
+	},
+}
+
+// diagnose prints the findings for the provided role and token claims.
+func diagnose(c *cli.Context) error {
+	in := &plugin.DiagnoseInput{
+		Role:               c.String("role"),
+		Audience:           c.String("audience"),
+		DurationSeconds:    c.Int("duration"),
+		MaxSessionDuration: c.Int("max-session-duration"),
+	}
+
+	switch {
+	case c.String("token") != "":
+		claims, err := plugin.DecodeClaims(c.String("token"))
+		if err != nil {
+			return err
+		}
+
+		in.Claims = claims
+	case c.String("claims") != "":
+		raw := []byte(c.String("claims"))
+		if !strings.HasPrefix(strings.TrimSpace(c.String("claims")), "{") {
+			data, err := os.ReadFile(c.String("claims"))
+			if err != nil {
+				return err
+			}
+
+			raw = data
+		}
+
+		err := json.Unmarshal(raw, &in.Claims)
+		if err != nil {
+			return fmt.Errorf("unable to parse claims: %w", err)
+		}
+	default:
+		return fmt.Errorf("one of --token or --claims must be provided")
+	}
+
+	if c.String("policy") != "" {
+		data, err := os.ReadFile(c.String("policy"))
+		if err != nil {
+			return err
+		}
+
+		in.TrustPolicy, err = plugin.ParsePolicyDocument(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.Bool("fetch") {
+		err := in.FetchRoleDetails(c.Context, c.String("region"))
+		if err != nil {
+			return err
+		}
+	}
+
+	var failed int
+
+	for _, f := range plugin.Diagnose(in) {
+		if !f.OK {
+			failed++
+		}
+
+		fmt.Fprintln(c.App.Writer, f)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("found %d likely cause(s) of failure for %s", failed, in.Role)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/Cargill/vela-aws-credentials/pkg/plugin"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/urfave/cli/v2"
+)
+
+// eksTokenCommand prints an EKS token for the kubeconfig exec plugin.
+var eksTokenCommand = &cli.Command{
+	Name:      "eks-token",
+	Usage:     "print an EKS token as a client authentication ExecCredential, for the kubeconfig exec plugin",
+	UsageText: "vela-aws-credentials eks-token --cluster <name> --region <region>",
+	Action:    eksToken,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "cluster",
+			Usage:    "name of the EKS cluster",
+			Required: true,
+		},
+		&cli.StringFlag{
+			EnvVars: []string{"AWS_REGION"},
+			Name:    "region",
+			Usage:   "AWS region of the EKS cluster",
+		},
+	},
+}
+
+// eksToken presigns an EKS token with the AWS credentials in the environment
+// and prints it as an ExecCredential.
+func eksToken(c *cli.Context) error {
+	cfg, err := config.LoadDefaultConfig(c.Context, config.WithRegion(c.String("region")))
+	if err != nil {
+		return err
+	}
+
+	token, expiresAt, err := plugin.EKSToken(c.Context, cfg, c.String("cluster"))
+	if err != nil {
+		return err
+	}
+
+	return json.NewEncoder(c.App.Writer).Encode(plugin.ExecCredential(token, expiresAt))
+}
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 	"time"
 
@@ -37,8 +38,30 @@ func main() {
 
 	app.Flags = plugin.Flags
 
+	// Plugin Commands
+
+	app.Commands = []*cli.Command{
+		diagnoseCommand,
+		eksTokenCommand,
+		generateTrustPolicyCommand,
+		schemaCommand,
+	}
+
 	err := app.Run(os.Args)
 	if err != nil {
-		logrus.Fatal(err)
+		fields := logrus.Fields{}
+
+		var pluginErr *plugin.Error
+		if errors.As(err, &pluginErr) {
+			fields["kind"] = pluginErr.Kind.String()
+
+			if hint := pluginErr.Hint(); hint != "" {
+				fields["hint"] = hint
+			}
+		}
+
+		logrus.WithFields(fields).Error(err)
+
+		os.Exit(plugin.ExitCode(err))
 	}
 }
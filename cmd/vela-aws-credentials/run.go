@@ -1,6 +1,10 @@
 package main
 
 import (
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/Cargill/vela-aws-credentials/pkg/plugin"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
@@ -8,9 +12,22 @@ import (
 
 // run executes the plugin based off the configuration provided.
 func run(c *cli.Context) error {
+	// apply the config file first so it may also set the log format and level;
+	// a failure is returned once the plugin is built so it is still audited
+	var configErr error
+	if path := c.String(plugin.FlagConfigFile); path != "" {
+		configErr = plugin.ApplyConfigFile(c, path)
+	}
+
 	// create a new, empty sirupsen/logrus logger
 	logger := logrus.New()
 
+	// redact the secrets known to the plugin from every log entry,
+	// including the error logged when the plugin exits
+	redactor := plugin.NewRedactor()
+	logger.AddHook(redactor)
+	logrus.AddHook(redactor)
+
 	// set the log format for the plugin
 	switch c.String(plugin.FlagLogFormat) {
 	case "text", "Text", "TEXT":
@@ -49,13 +66,33 @@ func run(c *cli.Context) error {
 
 	// create the plugin
 	p := plugin.FromCLIContext(c, logger.WithField("version", version))
+	p.Redactor = redactor
+
+	// the audit settings are admin secrets, so they are known even when the
+	// config file could not be applied
+	if configErr != nil {
+		p.Audit(c.Context, configErr)
+
+		return configErr
+	}
 
 	// validate the plugin
 	err := p.Validate()
 	if err != nil {
+		p.Audit(c.Context, err)
+
 		return err
 	}
 
+	// cancel the plugin cleanly when Vela stops the step
+	ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// execute the plugin
-	return p.Exec()
+	err = p.Exec(ctx)
+
+	// record the outcome, even when the plugin failed
+	p.Audit(ctx, err)
+
+	return err
 }
@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/Cargill/vela-aws-credentials/pkg/plugin"
+	"github.com/urfave/cli/v2"
+)
+
+// schemaCommand prints the JSON Schema of the config file.
+var schemaCommand = &cli.Command{
+	Name:      "schema",
+	Usage:     "print the JSON Schema of the config file",
+	UsageText: "vela-aws-credentials schema > vela-aws-credentials.schema.json",
+	Action:    schema,
+}
+
+// schema prints the JSON Schema of the config file.
+func schema(c *cli.Context) error {
+	data, err := plugin.Schema()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.App.Writer, string(data))
+
+	return nil
+}
@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/Cargill/vela-aws-credentials/pkg/plugin"
+	"github.com/urfave/cli/v2"
+)
+
+// generateTrustPolicyCommand prints a trust policy and OIDC provider for a repository.
+var generateTrustPolicyCommand = &cli.Command{
+	Name:      "generate-trust-policy",
+	Usage:     "print an IAM trust policy with Terraform and CloudFormation snippets for a Vela repository",
+	UsageText: "vela-aws-credentials generate-trust-policy --server <url> --org <org> [--repo <repo>] [--branch <branch>]... [--event <event>]...",
+	Action:    generateTrustPolicy,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "server",
+			Usage:    "URL of the Vela server (e.g. https://vela-server.com)",
+			Required: true,
+		},
+		&cli.StringFlag{
+			EnvVars: []string{"PARAMETER_AUDIENCE", "AWS_CREDENTIALS_AUDIENCE"},
+			Name:    "audience",
+			Usage:   "audience to register as the client ID of the OIDC provider",
+			Value:   "sts.amazonaws.com",
+		},
+		&cli.StringFlag{
+			Name:  "account-id",
+			Usage: "AWS account ID hosting the OIDC provider (defaults to an <ACCOUNT_ID> placeholder in the json format)",
+		},
+		&cli.StringFlag{
+			Name:  "partition",
+			Usage: "AWS partition of the OIDC provider ARN - options: (aws|aws-cn|aws-us-gov)",
+			Value: "aws",
+		},
+		&cli.StringFlag{
+			Name:  "role-name",
+			Usage: "name of the IAM role",
+			Value: "vela",
+		},
+		&cli.StringFlag{
+			Name:     "org",
+			Usage:    "Vela organization allowed to assume the role",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "repo",
+			Usage: "Vela repository allowed to assume the role (defaults to every repository in the org)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "branch",
+			Usage: "branch allowed to assume the role, may be repeated (defaults to every ref)",
+		},
+		&cli.StringSliceFlag{
+			Name:  "event",
+			Usage: "build event allowed to assume the role, may be repeated (defaults to every event)",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "output format - options: (json|terraform|cloudformation|all)",
+			Value: "all",
+		},
+	},
+}
+
+// generateTrustPolicy prints the trust policy in the requested formats.
+func generateTrustPolicy(c *cli.Context) error {
+	in := &plugin.TrustPolicyInput{
+		ServerURL: c.String("server"),
+		Audience:  c.String("audience"),
+		AccountID: c.String("account-id"),
+		Partition: c.String("partition"),
+		RoleName:  c.String("role-name"),
+		Org:       c.String("org"),
+		Repo:      c.String("repo"),
+		Branches:  c.StringSlice("branch"),
+		Events:    c.StringSlice("event"),
+	}
+
+	formats := map[string]func() (string, error){
+		"json":           in.JSON,
+		"terraform":      in.Terraform,
+		"cloudformation": in.CloudFormation,
+	}
+
+	order := []string{"json", "terraform", "cloudformation"}
+
+	switch c.String("format") {
+	case "all":
+	case "json", "terraform", "cloudformation":
+		order = []string{c.String("format")}
+	default:
+		return fmt.Errorf("unsupported format %q", c.String("format"))
+	}
+
+	for i, format := range order {
+		out, err := formats[format]()
+		if err != nil {
+			return err
+		}
+
+		if len(order) > 1 {
+			if i > 0 {
+				fmt.Fprintln(c.App.Writer)
+			}
+
+			fmt.Fprintf(c.App.Writer, "# %s\n\n", format)
+		}
+
+		fmt.Fprint(c.App.Writer, out)
+	}
+
+	return nil
+}
@@ -0,0 +1,294 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.yaml.in/yaml/v3"
+)
+
+// AllowlistPath represents the path of the role allowlist baked into the plugin image.
+const AllowlistPath = "/etc/vela-aws-credentials/allowlist.yml"
+
+// allowlistSubjectPattern captures the org, repo, ref and event of the Vela ID
+// token subject repo:<org>/<repo>:ref:<ref>:event:<event>.
+var allowlistSubjectPattern = regexp.MustCompile(`^repo:([^/:]+)/([^:]+):ref:([^:]+):event:([a-z_]+)$`)
+
+type (
+	// Allowlist represents the roles each Vela pipeline is allowed to assume.
+	Allowlist struct {
+		Rules []AllowlistRule `yaml:"rules"`
+	}
+
+	// AllowlistRule represents the roles allowed for pipelines matching the
+	// org, repo, branch and event patterns. Empty patterns match anything.
+	AllowlistRule struct {
+		Name                    string   `yaml:"name"`
+		Org                     string   `yaml:"org"`
+		Repo                    string   `yaml:"repo"`
+		Branch                  string   `yaml:"branch"`
+		Event                   string   `yaml:"event"`
+		Roles                   []string `yaml:"roles"`
+		MaxDuration             int      `yaml:"max_duration"`
+		RequiredSessionPolicies []string `yaml:"required_session_policies"`
+	}
+
+	// AllowlistRequest represents a role assumption to evaluate against the allowlist.
+	AllowlistRequest struct {
+		Org                    string
+		Repo                   string
+		Branch                 string
+		Event                  string
+		Role                   string
+		DurationSeconds        int
+		ManagedSessionPolicies []string
+	}
+)
+
+// ParseAllowlist parses a YAML or JSON allowlist, rejecting unknown keys.
+func ParseAllowlist(data []byte) (*Allowlist, error) {
+	allowlist := new(Allowlist)
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(allowlist)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse allowlist: %w", err)
+	}
+
+	for i, rule := range allowlist.Rules {
+		if len(rule.Roles) == 0 {
+			return nil, fmt.Errorf("allowlist rule %s has no roles", rule.name(i))
+		}
+	}
+
+	return allowlist, nil
+}
+
+// Evaluate returns the first rule that allows the request, or an error
+// explaining why each rule matching the pipeline denied it.
+func (a *Allowlist) Evaluate(req *AllowlistRequest) (*AllowlistRule, error) {
+	var reasons []string
+
+	for i := range a.Rules {
+		rule := &a.Rules[i]
+
+		if !rule.matchesPipeline(req) {
+			continue
+		}
+
+		reason := rule.deny(req)
+		if reason == "" {
+			return rule, nil
+		}
+
+		reasons = append(reasons, fmt.Sprintf("rule %s: %s", rule.name(i), reason))
+	}
+
+	if len(reasons) == 0 {
+		return nil, fmt.Errorf("no allowlist rule matches %s/%s on branch %q for event %q", req.Org, req.Repo, req.Branch, req.Event)
+	}
+
+	return nil, fmt.Errorf("role %s is not allowed for %s/%s on branch %q for event %q (%s)",
+		req.Role, req.Org, req.Repo, req.Branch, req.Event, strings.Join(reasons, "; "))
+}
+
+// matchesPipeline reports whether the rule applies to the pipeline of the request.
+func (r *AllowlistRule) matchesPipeline(req *AllowlistRequest) bool {
+	match := func(pattern, value string) bool {
+		return pattern == "" || matchWildcard(pattern, value)
+	}
+
+	return match(r.Org, req.Org) && match(r.Repo, req.Repo) && match(r.Branch, req.Branch) && match(r.Event, req.Event)
+}
+
+// deny returns the reason the rule denies the request, or an empty string when it is allowed.
+func (r *AllowlistRule) deny(req *AllowlistRequest) string {
+	if !slices.ContainsFunc(r.Roles, func(pattern string) bool { return matchWildcard(pattern, req.Role) }) {
+		return "role is not in the allowed roles"
+	}
+
+	if r.MaxDuration > 0 && req.DurationSeconds > r.MaxDuration {
+		return fmt.Sprintf("duration %ds exceeds the maximum of %ds", req.DurationSeconds, r.MaxDuration)
+	}
+
+	for _, policy := range r.RequiredSessionPolicies {
+		if !slices.Contains(req.ManagedSessionPolicies, policy) {
+			return fmt.Sprintf("required managed session policy %s is missing", policy)
+		}
+	}
+
+	return ""
+}
+
+// name returns the name of the rule for messages, falling back to its index.
+func (r *AllowlistRule) name(i int) string {
+	if r.Name != "" {
+		return fmt.Sprintf("%q", r.Name)
+	}
+
+	return fmt.Sprintf("#%d", i)
+}
+
+// validateAllowlist reads and parses the allowlist baked into the image and
+// the allowlist provided by an admin secret, so they are evaluated once the ID
+// token is known.
+func (c *Config) validateAllowlist() error {
+	c.allowlists = map[string]*Allowlist{}
+
+	sources := map[string][]byte{}
+
+	if c.AllowlistFile != "" {
+		data, err := os.ReadFile(c.AllowlistFile)
+
+		switch {
+		case err == nil:
+			sources[c.AllowlistFile] = data
+		case !errors.Is(err, os.ErrNotExist):
+			return fmt.Errorf("unable to read allowlist %s: %w", c.AllowlistFile, err)
+		}
+	}
+
+	if c.Allowlist != "" {
+		sources["allowlist secret"] = []byte(c.Allowlist)
+	}
+
+	for _, source := range sortedKeys(sources) {
+		allowlist, err := ParseAllowlist(sources[source])
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+
+		c.allowlists[source] = allowlist
+	}
+
+	return nil
+}
+
+// enforceAllowlists evaluates the role assumptions against the allowlists.
+// Every configured allowlist must allow every request. The pipeline is taken
+// from the subject of the ID token rather than the VELA_* variables of the
+// step, which the pipeline may override; a token with forged claims is
+// rejected by AWS when it is exchanged.
+func (c *Config) enforceAllowlists(token string) error {
+	return c.evaluateAllowlists(token, c.allowlistRequests)
+}
+
+// enforceRevokeAllowlists evaluates the revoke role and the role assumptions
+// of the step whose sessions the cleanup action revokes against the
+// allowlists, so a pipeline cannot revoke the sessions of a role it may not
+// assume.
+func (c *Config) enforceRevokeAllowlists(token string) error {
+	return c.evaluateAllowlists(token, func(pipeline *AllowlistRequest) []*AllowlistRequest {
+		revoke := *pipeline
+		revoke.Role = c.AWS.RevokeRole
+		revoke.DurationSeconds = minRoleDurationSeconds
+
+		return append([]*AllowlistRequest{&revoke}, c.allowlistRequests(pipeline)...)
+	})
+}
+
+// evaluateAllowlists evaluates the requests of the pipeline in the ID token
+// against every configured allowlist.
+func (c *Config) evaluateAllowlists(token string, requests func(pipeline *AllowlistRequest) []*AllowlistRequest) error {
+	if len(c.allowlists) == 0 {
+		return nil
+	}
+
+	claims, err := DecodeClaims(token)
+	if err != nil {
+		return newError(ErrorKindToken, err)
+	}
+
+	sub, _ := claims["sub"].(string)
+
+	pipeline, err := pipelineFromSubject(sub)
+	if err != nil {
+		return newError(ErrorKindDenied, err)
+	}
+
+	for _, req := range requests(pipeline) {
+		fields := logrus.Fields{
+			"org":      req.Org,
+			"repo":     req.Repo,
+			"branch":   req.Branch,
+			"event":    req.Event,
+			"role":     req.Role,
+			"duration": req.DurationSeconds,
+		}
+
+		for _, source := range sortedKeys(c.allowlists) {
+			rule, err := c.allowlists[source].Evaluate(req)
+			if err != nil {
+				c.Logger.WithFields(fields).WithField("allowlist", source).Warn("role assumption denied by allowlist")
+
+				return newError(ErrorKindDenied, fmt.Errorf("%s: %w", source, err))
+			}
+
+			c.Logger.WithFields(fields).WithFields(logrus.Fields{
+				"allowlist": source,
+				"rule":      rule.Name,
+			}).Info("role assumption allowed by allowlist")
+		}
+	}
+
+	return nil
+}
+
+// pipelineFromSubject returns the org, repo, branch and event of the Vela ID
+// token subject. The branch is the ref without refs/heads/, or the full ref
+// for tags and pull requests.
+func pipelineFromSubject(sub string) (*AllowlistRequest, error) {
+	match := allowlistSubjectPattern.FindStringSubmatch(sub)
+	if match == nil {
+		return nil, fmt.Errorf("ID token subject %q does not match the Vela format repo:<org>/<repo>:ref:<ref>:event:<event>", sub)
+	}
+
+	return &AllowlistRequest{
+		Org:    match[1],
+		Repo:   match[2],
+		Branch: strings.TrimPrefix(match[3], "refs/heads/"),
+		Event:  match[4],
+	}, nil
+}
+
+// allowlistRequests returns a request of the pipeline for each role the plugin
+// assumes: the role, or every role of the profile chain with the session
+// policies only applying to its last role. The roles of the chain are taken
+// from the chain itself, so the role the plugin ends up with is evaluated even
+// if it differs from the role parameter.
+func (c *Config) allowlistRequests(pipeline *AllowlistRequest) []*AllowlistRequest {
+	newRequest := func(role string, duration int, policies []string) *AllowlistRequest {
+		return &AllowlistRequest{
+			Org:                    pipeline.Org,
+			Repo:                   pipeline.Repo,
+			Branch:                 pipeline.Branch,
+			Event:                  pipeline.Event,
+			Role:                   role,
+			DurationSeconds:        duration,
+			ManagedSessionPolicies: policies,
+		}
+	}
+
+	if len(c.roleChain) <= 1 {
+		return []*AllowlistRequest{newRequest(c.AWS.Role, c.AWS.RoleDurationSeconds, c.AWS.ManagedSessionPolicies)}
+	}
+
+	reqs := make([]*AllowlistRequest, 0, len(c.roleChain))
+	leaf := len(c.roleChain) - 1
+
+	for i, role := range c.roleChain[:leaf] {
+		reqs = append(reqs, newRequest(role.RoleARN, c.chainDuration(i), nil))
+	}
+
+	return append(reqs, newRequest(c.roleChain[leaf].RoleARN, c.chainDuration(leaf), c.AWS.ManagedSessionPolicies))
+}
@@ -0,0 +1,279 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlugin_Allowlist_Evaluate(t *testing.T) {
+	data, err := os.ReadFile("testdata/allowlist.yml")
+	if err != nil {
+		t.Fatalf("unable to read allowlist: %v", err)
+	}
+
+	allowlist, err := ParseAllowlist(data)
+	if err != nil {
+		t.Fatalf("unable to parse allowlist: %v", err)
+	}
+
+	deploy := func() *AllowlistRequest {
+		return &AllowlistRequest{
+			Org:                    "octo-org",
+			Repo:                   "octo-repo",
+			Branch:                 "main",
+			Event:                  "push",
+			Role:                   "arn:aws:iam::123456123456:role/deploy-app",
+			DurationSeconds:        3600,
+			ManagedSessionPolicies: []string{"arn:aws:iam::123456123456:policy/boundary"},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		req      func() *AllowlistRequest
+		wantRule string
+		wantErr  string
+	}{
+		{
+			name:     "allowed deploy",
+			req:      deploy,
+			wantRule: "production deploys",
+		},
+		{
+			name: "allowed pull request",
+			req: func() *AllowlistRequest {
+				return &AllowlistRequest{
+					Org:    "octo-org",
+					Repo:   "octo-repo",
+					Branch: "feature",
+					Event:  "pull_request",
+					Role:   "arn:aws:iam::123456123456:role/read-only",
+				}
+			},
+			wantRule: "pull request builds",
+		},
+		{
+			name: "role not allowed",
+			req: func() *AllowlistRequest {
+				req := deploy()
+				req.Role = "arn:aws:iam::123456123456:role/admin"
+
+				return req
+			},
+			wantErr: "role is not in the allowed roles",
+		},
+		{
+			name: "duration above maximum",
+			req: func() *AllowlistRequest {
+				req := deploy()
+				req.DurationSeconds = 7200
+
+				return req
+			},
+			wantErr: "duration 7200s exceeds the maximum of 3600s",
+		},
+		{
+			name: "required session policy missing",
+			req: func() *AllowlistRequest {
+				req := deploy()
+				req.ManagedSessionPolicies = nil
+
+				return req
+			},
+			wantErr: "required managed session policy arn:aws:iam::123456123456:policy/boundary is missing",
+		},
+		{
+			name: "no matching rule",
+			req: func() *AllowlistRequest {
+				req := deploy()
+				req.Org = "other-org"
+
+				return req
+			},
+			wantErr: "no allowlist rule matches other-org/octo-repo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := allowlist.Evaluate(tt.req())
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantRule, rule.Name)
+		})
+	}
+}
+
+func TestPlugin_ParseAllowlist_Invalid(t *testing.T) {
+	_, err := ParseAllowlist([]byte("rules:\n  - org: octo-org\n    roles: [arn:aws:iam::123456123456:role/test]\n    role_arn: typo\n"))
+	assert.Error(t, err, "unknown keys should be rejected")
+
+	_, err = ParseAllowlist([]byte("rules:\n  - org: octo-org\n"))
+	assert.ErrorContains(t, err, "has no roles")
+}
+
+func TestPlugin_Validate_Allowlist(t *testing.T) {
+	config := func() *Config {
+		return &Config{
+			AWS: &AWS{
+				Region:              "us-east-1",
+				Role:                "arn:aws:iam::123456123456:role/read-only",
+				RoleDurationSeconds: 3600,
+			},
+			Build: &BuildContext{
+				Org:    "octo-org",
+				Repo:   "octo-repo",
+				Branch: "feature",
+				Event:  "pull_request",
+			},
+			//nolint:gosec // ignore false positive for hardcoded credential
+			Vela: &Vela{
+				RequestToken:    "testToken",
+				RequestTokenURL: "http://127.0.0.1",
+			},
+			Logger:       logrus.NewEntry(logrus.StandardLogger()),
+			ScriptFormat: ScriptFormatShell,
+		}
+	}
+
+	pullRequest := allowlistTestToken(t, "repo:octo-org/octo-repo:ref:refs/pull/1/head:event:pull_request")
+
+	t.Run("missing image allowlist is ignored", func(t *testing.T) {
+		c := config()
+		c.AllowlistFile = filepath.Join(t.TempDir(), "allowlist.yml")
+
+		assert.NoError(t, c.Validate())
+		assert.NoError(t, c.enforceAllowlists(pullRequest))
+	})
+
+	t.Run("invalid secret allowlist", func(t *testing.T) {
+		c := config()
+		c.Allowlist = `{"rules": [{"org": "octo-org"}]}`
+
+		assert.ErrorContains(t, c.Validate(), "allowlist secret: allowlist rule #0 has no roles")
+	})
+
+	t.Run("image allowlist allows", func(t *testing.T) {
+		c := config()
+		c.AllowlistFile = "testdata/allowlist.yml"
+
+		assert.NoError(t, c.Validate())
+		assert.NoError(t, c.enforceAllowlists(pullRequest))
+	})
+
+	t.Run("secret allowlist denies", func(t *testing.T) {
+		c := config()
+		c.AllowlistFile = "testdata/allowlist.yml"
+		c.Allowlist = `{"rules": [{"org": "octo-org", "event": "push", "roles": ["*"]}]}`
+
+		assert.NoError(t, c.Validate())
+
+		err := c.enforceAllowlists(pullRequest)
+
+		var pluginErr *Error
+		if assert.True(t, errors.As(err, &pluginErr)) {
+			assert.Equal(t, ErrorKindDenied, pluginErr.Kind)
+		}
+
+		assert.ErrorContains(t, err, "allowlist secret")
+	})
+
+	t.Run("build variables are not trusted", func(t *testing.T) {
+		c := config()
+		c.AllowlistFile = "testdata/allowlist.yml"
+		c.AWS.Role = "arn:aws:iam::123456123456:role/deploy-production"
+		c.AWS.ManagedSessionPolicies = []string{"arn:aws:iam::123456123456:policy/boundary"}
+
+		// the pipeline overrides the variables to look like a push to main
+		c.Build.Branch = "main"
+		c.Build.Event = "push"
+
+		assert.NoError(t, c.Validate())
+
+		err := c.enforceAllowlists(pullRequest)
+		assert.Equal(t, ErrorKindDenied.ExitCode(), ExitCode(err))
+		assert.ErrorContains(t, err, `not allowed for octo-org/octo-repo on branch "refs/pull/1/head" for event "pull_request"`)
+
+		push := allowlistTestToken(t, "repo:octo-org/octo-repo:ref:refs/heads/main:event:push")
+
+		assert.NoError(t, c.enforceAllowlists(push))
+	})
+
+	t.Run("malformed subject", func(t *testing.T) {
+		c := config()
+		c.AllowlistFile = "testdata/allowlist.yml"
+
+		assert.NoError(t, c.Validate())
+
+		err := c.enforceAllowlists(allowlistTestToken(t, "octo-org/octo-repo"))
+		assert.Equal(t, ErrorKindDenied.ExitCode(), ExitCode(err))
+		assert.ErrorContains(t, err, "does not match the Vela format")
+	})
+
+	t.Run("every role of the profile chain is evaluated", func(t *testing.T) {
+		c := config()
+		c.AllowlistFile = "testdata/allowlist.yml"
+
+		assert.NoError(t, c.validateAllowlist())
+
+		c.roleChain = []ProfileRole{
+			{Profile: "vela", RoleARN: "arn:aws:iam::123456123456:role/admin"},
+			{Profile: "read-only", RoleARN: "arn:aws:iam::123456123456:role/read-only"},
+		}
+
+		err := c.enforceAllowlists(pullRequest)
+		assert.Equal(t, ErrorKindDenied.ExitCode(), ExitCode(err))
+		assert.ErrorContains(t, err, "role arn:aws:iam::123456123456:role/admin is not allowed")
+
+		c.roleChain[0].RoleARN = "arn:aws:iam::123456123456:role/read-only"
+
+		assert.NoError(t, c.enforceAllowlists(pullRequest))
+
+		// the leaf of the chain is evaluated rather than the role parameter
+		c.roleChain[1].RoleARN = "arn:aws:iam::123456123456:role/admin"
+
+		err = c.enforceAllowlists(pullRequest)
+		assert.Equal(t, ErrorKindDenied.ExitCode(), ExitCode(err))
+		assert.ErrorContains(t, err, "role arn:aws:iam::123456123456:role/admin is not allowed")
+	})
+
+	t.Run("revoke role is evaluated", func(t *testing.T) {
+		c := config()
+		c.AllowlistFile = "testdata/allowlist.yml"
+		c.AWS.RevokeRole = "arn:aws:iam::123456123456:role/revoke"
+
+		assert.NoError(t, c.validateAllowlist())
+
+		err := c.enforceRevokeAllowlists(pullRequest)
+		assert.Equal(t, ErrorKindDenied.ExitCode(), ExitCode(err))
+		assert.ErrorContains(t, err, "role arn:aws:iam::123456123456:role/revoke is not allowed")
+
+		c.AWS.RevokeRole = "arn:aws:iam::123456123456:role/read-only"
+
+		assert.NoError(t, c.enforceRevokeAllowlists(pullRequest))
+	})
+}
+
+// allowlistTestToken returns an unsigned ID token with the subject.
+func allowlistTestToken(t *testing.T, sub string) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": sub}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("unable to create token: %v", err)
+	}
+
+	return token
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// minRoleDurationSeconds represents the shortest duration STS allows for an assumed role session.
+	minRoleDurationSeconds = 900
+)
+
+var (
+	// roleARNPattern matches an IAM role ARN, capturing the partition and account ID.
+	roleARNPattern = regexp.MustCompile(`^arn:([a-z-]+):iam::(\d{12}):role/((?:[\x21-\x7E]+/)*)?[\w+=,.@-]{1,64}$`)
+
+	// policyARNPattern matches an IAM managed policy ARN, including AWS managed policies.
+	policyARNPattern = regexp.MustCompile(`^arn:([a-z-]+):iam::(\d{12}|aws):policy/((?:[\x21-\x7E]+/)*)?[\w+=,.@-]{1,128}$`)
+
+	// partitionRegions represents the known regions of each AWS partition.
+	partitionRegions = map[string][]string{
+		"aws": {
+			"af-south-1",
+			"ap-east-1", "ap-east-2",
+			"ap-northeast-1", "ap-northeast-2", "ap-northeast-3",
+			"ap-south-1", "ap-south-2",
+			"ap-southeast-1", "ap-southeast-2", "ap-southeast-3", "ap-southeast-4", "ap-southeast-5", "ap-southeast-6", "ap-southeast-7",
+			"ca-central-1", "ca-west-1",
+			"eu-central-1", "eu-central-2",
+			"eu-north-1",
+			"eu-south-1", "eu-south-2",
+			"eu-west-1", "eu-west-2", "eu-west-3",
+			"il-central-1",
+			"me-central-1", "me-south-1",
+			"mx-central-1",
+			"sa-east-1",
+			"us-east-1", "us-east-2",
+			"us-west-1", "us-west-2",
+		},
+		"aws-cn":     {"cn-north-1", "cn-northwest-1"},
+		"aws-eusc":   {"eusc-de-east-1"},
+		"aws-iso":    {"us-iso-east-1", "us-iso-west-1"},
+		"aws-iso-b":  {"us-isob-east-1"},
+		"aws-iso-e":  {"eu-isoe-west-1"},
+		"aws-iso-f":  {"us-isof-east-1", "us-isof-south-1"},
+		"aws-us-gov": {"us-gov-east-1", "us-gov-west-1"},
+	}
+)
+
+// RoleARN represents the parts of an IAM role ARN used by the plugin.
+type RoleARN struct {
+	Partition string
+	AccountID string
+	Name      string
+}
+
+// ParseRoleARN parses and validates the structure of an IAM role ARN.
+func ParseRoleARN(arn string) (*RoleARN, error) {
+	match := roleARNPattern.FindStringSubmatch(arn)
+	if match == nil {
+		return nil, fmt.Errorf("role %q is not a valid IAM role ARN (expected arn:<partition>:iam::<account id>:role/[<path>/]<name>)", arn)
+	}
+
+	if _, ok := partitionRegions[match[1]]; !ok {
+		return nil, fmt.Errorf("role %q uses unknown partition %q", arn, match[1])
+	}
+
+	return &RoleARN{
+		Partition: match[1],
+		AccountID: match[2],
+		Name:      arn[strings.LastIndex(arn, "/")+1:],
+	}, nil
+}
+
+// validatePolicyARN validates the structure of an IAM managed policy ARN.
+func validatePolicyARN(arn string) error {
+	match := policyARNPattern.FindStringSubmatch(arn)
+	if match == nil {
+		return fmt.Errorf("managed session policy %q is not a valid IAM policy ARN (expected arn:<partition>:iam::<account id|aws>:policy/[<path>/]<name>)", arn)
+	}
+
+	if _, ok := partitionRegions[match[1]]; !ok {
+		return fmt.Errorf("managed session policy %q uses unknown partition %q", arn, match[1])
+	}
+
+	return nil
+}
+
+// validateRegion validates that the region is known in the partition of the role.
+func validateRegion(region, partition string, additional []string) error {
+	if region == "" {
+		return fmt.Errorf("no region provided")
+	}
+
+	if slices.Contains(additional, region) {
+		return nil
+	}
+
+	if slices.Contains(partitionRegions[partition], region) {
+		return nil
+	}
+
+	for p, regions := range partitionRegions {
+		if slices.Contains(regions, region) {
+			return fmt.Errorf("region %q belongs to partition %q but the role is in partition %q", region, p, partition)
+		}
+	}
+
+	return fmt.Errorf("region %q is not a known AWS region - add it to additional_regions if it was recently launched", region)
+}
+
+// validateDuration validates that a role duration is within the bounds allowed by STS.
+func validateDuration(name string, duration int) error {
+	if duration < minRoleDurationSeconds || duration > maxRoleDurationSeconds {
+		return fmt.Errorf("%s %d must be between %d and %d seconds", name, duration, minRoleDurationSeconds, maxRoleDurationSeconds)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// auditWebhookTimeout represents the time allowed to deliver an audit event to the webhook.
+const auditWebhookTimeout = 10 * time.Second
+
+// Outcomes of a credential issuance recorded in audit events.
+const (
+	auditOutcomeSuccess = "success"
+	auditOutcomeFailure = "failure"
+	auditOutcomeDryRun  = "dry_run"
+	auditOutcomeCleanup = "cleanup"
+)
+
+// AuditEvent represents the record of a single credential issuance. It never
+// contains the ID token or the credentials.
+type AuditEvent struct {
+	Time              time.Time  `json:"time"`
+	Outcome           string     `json:"outcome"`
+	ErrorKind         string     `json:"error_kind,omitempty"`
+	Error             string     `json:"error,omitempty"`
+	Org               string     `json:"org"`
+	Repo              string     `json:"repo"`
+	BuildNumber       int        `json:"build_number"`
+	BuildLink         string     `json:"build_link,omitempty"`
+	Branch            string     `json:"branch,omitempty"`
+	Event             string     `json:"event,omitempty"`
+	Commit            string     `json:"commit,omitempty"`
+	Author            string     `json:"author,omitempty"`
+	Subject           string     `json:"sub,omitempty"`
+	TokenID           string     `json:"jti,omitempty"`
+	Role              string     `json:"role_arn"`
+	AssumedRole       string     `json:"assumed_role_arn,omitempty"`
+	SessionName       string     `json:"session_name,omitempty"`
+	SourceIdentity    string     `json:"source_identity,omitempty"`
+	DurationSeconds   int        `json:"duration_seconds"`
+	SessionPolicyHash string     `json:"session_policy_hash,omitempty"`
+	Expiration        *time.Time `json:"expiration,omitempty"`
+}
+
+// auditEvent returns the audit event for the plugin run that ended with err.
+func (c *Config) auditEvent(err error) *AuditEvent {
+	event := &AuditEvent{
+		Time:              time.Now().UTC(),
+		Outcome:           auditOutcomeSuccess,
+		Subject:           c.tokenSubject,
+		TokenID:           c.tokenID,
+		Role:              c.AWS.Role,
+		SessionName:       c.AWS.RoleSessionName,
+		DurationSeconds:   c.AWS.RoleDurationSeconds,
+		SessionPolicyHash: c.sessionPolicyHash(),
+	}
+
+	switch {
+	case c.Action == ActionCleanup:
+		event.Outcome = auditOutcomeCleanup
+	case c.DryRun:
+		event.Outcome = auditOutcomeDryRun
+	}
+
+	if err != nil {
+		event.Outcome = auditOutcomeFailure
+		event.Error = err.Error()
+
+		if c.Redactor != nil {
+			event.Error = c.Redactor.Redact(event.Error)
+		}
+
+		var pluginErr *Error
+		if errors.As(err, &pluginErr) {
+			event.ErrorKind = pluginErr.Kind.String()
+		}
+	}
+
+	if c.Build != nil {
+		event.Org = c.Build.Org
+		event.Repo = c.Build.Repo
+		event.BuildNumber = c.Build.BuildNumber
+		event.BuildLink = c.Build.Link
+		event.Branch = c.Build.Branch
+		event.Event = c.Build.Event
+		event.Commit = c.Build.Commit
+		event.Author = c.Build.Author
+	}
+
+	if c.Session != nil {
+		event.AssumedRole = c.Session.AssumedRoleARN
+		event.SessionName = c.Session.SessionName
+		event.SourceIdentity = c.Session.SourceIdentity
+		event.DurationSeconds = c.Session.DurationSeconds
+
+		if !c.Session.Expiration.IsZero() {
+			expiration := c.Session.Expiration.UTC()
+			event.Expiration = &expiration
+		}
+	}
+
+	return event
+}
+
+// sessionPolicyHash returns the SHA-256 of the inline and managed session
+// policies, or an empty string when no session policy is used.
+func (c *Config) sessionPolicyHash() string {
+	if c.inlineSessionPolicy == "" && len(c.AWS.ManagedSessionPolicies) == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(c.inlineSessionPolicy + "\n" + strings.Join(c.AWS.ManagedSessionPolicies, "\n")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Audit records the outcome of the plugin run in the log and, when
+// configured, the audit file and webhook. The event is written to the log
+// output as a JSON line rather than logged, so neither log_level nor
+// log_format can hide or re-encode it. Failing to deliver the audit event is
+// logged without failing the plugin.
+func (c *Config) Audit(ctx context.Context, err error) {
+	data, jsonErr := json.Marshal(c.auditEvent(err))
+	if jsonErr != nil {
+		c.Logger.Warnf("unable to encode audit event: %v", jsonErr)
+
+		return
+	}
+
+	_, writeErr := c.Logger.Logger.Out.Write(append(data, '\n'))
+	if writeErr != nil {
+		c.Logger.Warnf("unable to write audit event to the log: %v", writeErr)
+	}
+
+	if c.AuditFile != "" {
+		err := appendAuditEvent(c.AuditFile, data)
+		if err != nil {
+			c.Logger.Warnf("unable to write audit event to %s: %v", c.AuditFile, err)
+		}
+	}
+
+	if c.AuditWebhook != "" {
+		err := postAuditEvent(ctx, c.AuditWebhook, data)
+		if err != nil {
+			c.Logger.Warnf("unable to send audit event to webhook: %v", err)
+		}
+	}
+}
+
+// appendAuditEvent appends the audit event to the file at path as a single JSON line.
+func appendAuditEvent(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+
+	return err
+}
+
+// postAuditEvent sends the audit event to the webhook as JSON.
+func postAuditEvent(ctx context.Context, url string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditWebhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
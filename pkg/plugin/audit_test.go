@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Audit(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantOutcome string
+		wantKind    string
+	}{
+		{
+			name:        "success",
+			wantOutcome: auditOutcomeSuccess,
+		},
+		{
+			name:        "failure",
+			err:         newError(ErrorKindAccessDenied, errors.New("not authorized")),
+			wantOutcome: auditOutcomeFailure,
+			wantKind:    "access_denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posted []byte
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				posted, _ = io.ReadAll(r.Body)
+
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			t.Cleanup(srv.Close)
+
+			auditFile := filepath.Join(t.TempDir(), "audit.json")
+
+			var logged bytes.Buffer
+
+			c := &Config{
+				AuditFile:    auditFile,
+				AuditWebhook: srv.URL,
+				AWS: &AWS{
+					Role:                   "arn:aws:iam::123456123456:role/test",
+					RoleSessionName:        "vela",
+					RoleDurationSeconds:    3600,
+					ManagedSessionPolicies: []string{"arn:aws:iam::123456123456:policy/boundary"},
+				},
+				Build: &BuildContext{
+					Org:         "octo-org",
+					Repo:        "octo-repo",
+					BuildNumber: 42,
+				},
+				//nolint:gosec // ignore false positive for hardcoded credential
+				Vela: &Vela{
+					RequestToken: "REQUEST_TOKEN",
+				},
+				Session: &Session{
+					AssumedRoleARN:  "arn:aws:sts::123456123456:assumed-role/test/vela",
+					SessionName:     "vela",
+					DurationSeconds: 900,
+					Expiration:      time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+				},
+				// the event is written to the log regardless of the log level
+				Logger:       logrus.NewEntry(&logrus.Logger{Out: &logged, Formatter: &logrus.JSONFormatter{}, Level: logrus.PanicLevel}),
+				tokenSubject: "repo:octo-org/octo-repo:ref:refs/heads/main:event:push",
+				tokenID:      "token-id",
+			}
+
+			c.Audit(context.Background(), tt.err)
+
+			written, err := os.ReadFile(auditFile)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(written), string(posted), "file and webhook should receive the same event")
+			assert.Equal(t, string(written), logged.String())
+			assert.NotContains(t, string(written), "REQUEST_TOKEN")
+
+			event := new(AuditEvent)
+			assert.NoError(t, json.Unmarshal(written, event))
+			assert.Equal(t, tt.wantOutcome, event.Outcome)
+			assert.Equal(t, tt.wantKind, event.ErrorKind)
+			assert.Equal(t, "octo-org", event.Org)
+			assert.Equal(t, 42, event.BuildNumber)
+			assert.Equal(t, "repo:octo-org/octo-repo:ref:refs/heads/main:event:push", event.Subject)
+			assert.Equal(t, "token-id", event.TokenID)
+			assert.Equal(t, "arn:aws:sts::123456123456:assumed-role/test/vela", event.AssumedRole)
+			assert.Equal(t, 900, event.DurationSeconds)
+			assert.Len(t, event.SessionPolicyHash, 64)
+			assert.Equal(t, time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), *event.Expiration)
+		})
+	}
+}
+
+func TestConfig_Audit_WebhookFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	logger, hook := test.NewNullLogger()
+
+	c := &Config{
+		AuditWebhook: srv.URL,
+		AWS:          &AWS{Role: "arn:aws:iam::123456123456:role/test"},
+		Logger:       logrus.NewEntry(logger),
+	}
+
+	c.Audit(context.Background(), nil)
+
+	if assert.NotNil(t, hook.LastEntry()) {
+		assert.Equal(t, logrus.WarnLevel, hook.LastEntry().Level)
+		assert.Contains(t, hook.LastEntry().Message, "status 500")
+	}
+}
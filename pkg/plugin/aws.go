@@ -7,30 +7,36 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/aws-sdk-go-v2/service/sts/types"
-	"github.com/sirupsen/logrus"
 )
 
-func (c *Config) AssumeRole(token string) (*aws.Credentials, error) {
-	ctx := context.Background()
+// Session represents the non-secret details of the assumed role session.
+type Session struct {
+	AssumedRoleARN  string
+	AssumedRoleID   string
+	AccountID       string
+	SessionName     string
+	SourceIdentity  string
+	DurationSeconds int
+	Expiration      time.Time
+}
 
+func (c *Config) AssumeRole(ctx context.Context, token string) (*aws.Credentials, error) {
 	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(c.AWS.Region))
 	if err != nil {
 		return nil, err
 	}
 
-	// create an STS client
-	stsClient := sts.NewFromConfig(cfg)
-
-	var managedPolicies []types.PolicyDescriptorType
-	for _, policy := range c.AWS.ManagedSessionPolicies {
-		managedPolicies = append(managedPolicies, types.PolicyDescriptorType{Arn: aws.String(policy)})
-	}
+	// create an STS client, leaving retries to the plugin's retry policy
+	stsClient := sts.NewFromConfig(cfg, func(o *sts.Options) {
+		o.Retryer = aws.NopRetryer{}
+	})
 
 	input := &sts.AssumeRoleWithWebIdentityInput{
 		RoleArn:          aws.String(c.AWS.Role),
@@ -40,43 +46,216 @@ func (c *Config) AssumeRole(token string) (*aws.Credentials, error) {
 		DurationSeconds: aws.Int32(int32(c.AWS.RoleDurationSeconds)),
 	}
 
-	if c.AWS.InlineSessionPolicy != "" {
-		input.Policy = aws.String(c.AWS.InlineSessionPolicy)
-	}
+	// with a profile chain, the web identity assumes the role at its root and
+	// the session policies scope down the role of the profile instead
+	if len(c.roleChain) > 1 {
+		root := c.roleChain[0]
 
-	if len(managedPolicies) > 0 {
-		input.PolicyArns = managedPolicies
+		input.RoleArn = aws.String(root.RoleARN)
+		input.RoleSessionName = aws.String(c.chainSessionName(0))
+		//nolint:gosec // disable G115
+		input.DurationSeconds = aws.Int32(int32(c.chainDuration(0)))
+	} else {
+		input.Policy, input.PolicyArns = c.sessionPolicies()
 	}
 
 	// Perform the AssumeRoleWithWebIdentity request
-	assumeRoleOutput, err := stsClient.AssumeRoleWithWebIdentity(ctx, input)
+	assumeRoleOutput, err := c.assumeRoleWithWebIdentity(ctx, stsClient, input)
+	if err != nil && c.AWS.DurationFallback && isDurationTooLongError(err) {
+		assumeRoleOutput, err = c.assumeRoleWithFallback(ctx, stsClient, input, err)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("failed to assume role: %w", err)
 	}
 
-	creds := aws.Credentials{
-		AccessKeyID:     *assumeRoleOutput.Credentials.AccessKeyId,
-		SecretAccessKey: *assumeRoleOutput.Credentials.SecretAccessKey,
-		SessionToken:    *assumeRoleOutput.Credentials.SessionToken,
+	c.Logger.Infof("assumed role %s with a duration of %ds", aws.ToString(input.RoleArn), aws.ToInt32(input.DurationSeconds))
+
+	output := &sts.AssumeRoleOutput{
+		Credentials:     assumeRoleOutput.Credentials,
+		AssumedRoleUser: assumeRoleOutput.AssumedRoleUser,
+		SourceIdentity:  assumeRoleOutput.SourceIdentity,
 	}
+	duration := aws.ToInt32(input.DurationSeconds)
 
-	if c.Verify {
-		tempCfg, err := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(credentials.StaticCredentialsProvider{Value: creds}), config.WithRegion(c.AWS.Region))
+	if len(c.roleChain) > 1 {
+		output, duration, err = c.assumeRoleChain(ctx, cfg, output.Credentials)
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	creds := aws.Credentials{
+		AccessKeyID:     *output.Credentials.AccessKeyId,
+		SecretAccessKey: *output.Credentials.SecretAccessKey,
+		SessionToken:    *output.Credentials.SessionToken,
+	}
+
+	if output.Credentials.Expiration != nil {
+		creds.CanExpire = true
+		creds.Expires = *output.Credentials.Expiration
+	}
+
+	c.Session = &Session{
+		SessionName:     c.AWS.RoleSessionName,
+		SourceIdentity:  aws.ToString(output.SourceIdentity),
+		DurationSeconds: int(duration),
+		Expiration:      creds.Expires,
+	}
+
+	if output.AssumedRoleUser != nil {
+		c.Session.AssumedRoleARN = aws.ToString(output.AssumedRoleUser.Arn)
+		c.Session.AssumedRoleID = aws.ToString(output.AssumedRoleUser.AssumedRoleId)
+	}
+
+	if role, err := ParseRoleARN(c.AWS.Role); err == nil {
+		c.Session.AccountID = role.AccountID
+	}
+
+	return &creds, nil
+}
+
+// sessionPolicies returns the inline and managed session policies for the role assumption.
+func (c *Config) sessionPolicies() (*string, []types.PolicyDescriptorType) {
+	var inlinePolicy *string
+	if c.inlineSessionPolicy != "" {
+		inlinePolicy = aws.String(c.inlineSessionPolicy)
+	}
+
+	var managedPolicies []types.PolicyDescriptorType
+	for _, policy := range c.AWS.ManagedSessionPolicies {
+		managedPolicies = append(managedPolicies, types.PolicyDescriptorType{Arn: aws.String(policy)})
+	}
+
+	return inlinePolicy, managedPolicies
+}
+
+// assumeRoleChain assumes the roles of the profile's source_profile chain after
+// the root, each with the credentials of the previous role, and returns the
+// output and duration of the role of the profile.
+func (c *Config) assumeRoleChain(ctx context.Context, cfg aws.Config, creds *types.Credentials) (*sts.AssumeRoleOutput, int32, error) {
+	var (
+		output   *sts.AssumeRoleOutput
+		duration int32
+	)
+
+	for i := 1; i < len(c.roleChain); i++ {
+		role := c.roleChain[i]
+
+		c.redact(aws.ToString(creds.AccessKeyId), aws.ToString(creds.SecretAccessKey), aws.ToString(creds.SessionToken))
 
-		tempClient := sts.NewFromConfig(tempCfg)
+		client := sts.NewFromConfig(cfg, func(o *sts.Options) {
+			o.Retryer = aws.NopRetryer{}
+			o.Credentials = credentials.NewStaticCredentialsProvider(
+				aws.ToString(creds.AccessKeyId),
+				aws.ToString(creds.SecretAccessKey),
+				aws.ToString(creds.SessionToken),
+			)
+		})
 
-		_, err = tempClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		//nolint:gosec // disable G115
+		duration = int32(c.chainDuration(i))
+
+		input := &sts.AssumeRoleInput{
+			RoleArn:         aws.String(role.RoleARN),
+			RoleSessionName: aws.String(c.chainSessionName(i)),
+			DurationSeconds: aws.Int32(duration),
+		}
+
+		if role.ExternalID != "" {
+			input.ExternalId = aws.String(role.ExternalID)
+		}
+
+		if i == len(c.roleChain)-1 {
+			input.Policy, input.PolicyArns = c.sessionPolicies()
+		}
+
+		err := c.Retry.Do(ctx, c.Logger, "STS AssumeRole", isRetryableSTSError, func(ctx context.Context) error {
+			var err error
+
+			output, err = client.AssumeRole(ctx, input)
+
+			return err
+		})
 		if err != nil {
-			return nil, err
+			return nil, 0, fmt.Errorf("failed to assume role %s of profile %s: %w", role.RoleARN, role.Profile, err)
 		}
 
-		logrus.Infof("successfully validated credentials for %s", c.AWS.Role)
+		c.Logger.Infof("assumed role %s of profile %s with a duration of %ds", role.RoleARN, role.Profile, duration)
+
+		creds = output.Credentials
 	}
 
-	return &creds, nil
+	return output, duration, nil
+}
+
+// chainDuration returns the duration for the role at index i of the profile
+// chain. Roles before the role of the profile are only used to assume the
+// next role, so they default to the shortest duration.
+func (c *Config) chainDuration(i int) int {
+	switch {
+	case c.roleChain[i].DurationSeconds > 0:
+		return c.roleChain[i].DurationSeconds
+	case i == len(c.roleChain)-1:
+		return c.AWS.RoleDurationSeconds
+	default:
+		return minRoleDurationSeconds
+	}
+}
+
+// chainSessionName returns the session name for the role at index i of the
+// profile chain. The role of the profile uses the rendered role session name,
+// which already holds the session name of its profile, so the verified
+// identity matches the session.
+func (c *Config) chainSessionName(i int) string {
+	switch {
+	case i == len(c.roleChain)-1:
+		return c.AWS.RoleSessionName
+	case c.roleChain[i].SessionName != "":
+		return sanitizeRoleSessionName(c.roleChain[i].SessionName)
+	default:
+		return c.AWS.RoleSessionName
+	}
+}
+
+// assumeRoleWithWebIdentity performs the AssumeRoleWithWebIdentity request with the retry policy.
+func (c *Config) assumeRoleWithWebIdentity(ctx context.Context, client *sts.Client, input *sts.AssumeRoleWithWebIdentityInput) (*sts.AssumeRoleWithWebIdentityOutput, error) {
+	var output *sts.AssumeRoleWithWebIdentityOutput
+
+	err := c.Retry.Do(ctx, c.Logger, "STS AssumeRoleWithWebIdentity", isRetryableSTSError, func(ctx context.Context) error {
+		var err error
+
+		output, err = client.AssumeRoleWithWebIdentity(ctx, input)
+
+		return err
+	})
+
+	return output, err
+}
+
+// assumeRoleWithFallback retries the role assumption with each duration of the
+// ladder that is shorter than the rejected duration until one is accepted.
+func (c *Config) assumeRoleWithFallback(ctx context.Context, client *sts.Client, input *sts.AssumeRoleWithWebIdentityInput, err error) (*sts.AssumeRoleWithWebIdentityOutput, error) {
+	for _, duration := range c.AWS.DurationLadder {
+		if duration >= int(aws.ToInt32(input.DurationSeconds)) {
+			continue
+		}
+
+		c.Logger.Warnf("duration of %ds exceeds the maximum session duration of %s, retrying with %ds",
+			aws.ToInt32(input.DurationSeconds), aws.ToString(input.RoleArn), duration)
+
+		//nolint:gosec // disable G115
+		input.DurationSeconds = aws.Int32(int32(duration))
+
+		var output *sts.AssumeRoleWithWebIdentityOutput
+
+		output, err = c.assumeRoleWithWebIdentity(ctx, client, input)
+		if err == nil || !isDurationTooLongError(err) {
+			return output, err
+		}
+	}
+
+	return nil, err
 }
 
 func (c *Config) WriteCreds(creds *aws.Credentials) error {
@@ -3,13 +3,19 @@
 package plugin
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -83,3 +89,143 @@ func TestConfig_WriteCreds(t *testing.T) {
 		})
 	}
 }
+
+// stsCredentialsResponse represents a successful AssumeRoleWithWebIdentity response from STS.
+const stsCredentialsResponse = `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ACCESS_KEY_ID</AccessKeyId>
+      <SecretAccessKey>SECRET_ACCESS_KEY</SecretAccessKey>
+      <SessionToken>SESSION_TOKEN</SessionToken>
+      <Expiration>2030-01-01T00:00:00Z</Expiration>
+    </Credentials>
+    <AssumedRoleUser>
+      <Arn>arn:aws:sts::123456123456:assumed-role/test/vela</Arn>
+      <AssumedRoleId>AROAEXAMPLE:vela</AssumedRoleId>
+    </AssumedRoleUser>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`
+
+// stsErrorResponse represents an error response from STS.
+const stsErrorResponse = `<ErrorResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <Error>
+    <Type>Sender</Type>
+    <Code>%s</Code>
+    <Message>%s</Message>
+  </Error>
+  <RequestId>00000000-0000-0000-0000-000000000000</RequestId>
+</ErrorResponse>`
+
+// newTestAWS starts a local endpoint for every AWS service that answers
+// requests with handler and points the AWS SDK at it for the duration of the test.
+func newTestAWS(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	t.Setenv("AWS_ENDPOINT_URL", srv.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "TEST")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "TEST")
+}
+
+// newTestSTS starts a local STS endpoint that answers requests with handler
+// and points the AWS SDK at it for the duration of the test.
+func newTestSTS(t *testing.T, handler func(w http.ResponseWriter, form url.Values)) {
+	t.Helper()
+
+	newTestAWS(t, func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			t.Errorf("unable to parse STS request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "text/xml")
+		handler(w, r.PostForm)
+	})
+}
+
+func TestConfig_AssumeRole_DurationFallback(t *testing.T) {
+	tests := []struct {
+		name         string
+		fallback     bool
+		maxDuration  int
+		wantDuration []string
+		wantErr      bool
+	}{
+		{
+			name:         "duration within maximum",
+			fallback:     true,
+			maxDuration:  43200,
+			wantDuration: []string{"43200"},
+		},
+		{
+			name:         "falls back to the largest allowed duration",
+			fallback:     true,
+			maxDuration:  3600,
+			wantDuration: []string{"43200", "21600", "3600"},
+		},
+		{
+			name:         "fallback disabled",
+			maxDuration:  3600,
+			wantDuration: []string{"43200"},
+			wantErr:      true,
+		},
+		{
+			name:         "ladder exhausted",
+			fallback:     true,
+			maxDuration:  600,
+			wantDuration: []string{"43200", "21600", "3600", "900"},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+
+			newTestSTS(t, func(w http.ResponseWriter, form url.Values) {
+				got = append(got, form.Get("DurationSeconds"))
+
+				duration, _ := strconv.Atoi(form.Get("DurationSeconds"))
+				if duration > tt.maxDuration {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprintf(w, stsErrorResponse, "ValidationError", "The requested DurationSeconds exceeds the MaxSessionDuration set for this role.")
+
+					return
+				}
+
+				fmt.Fprint(w, stsCredentialsResponse)
+			})
+
+			c := &Config{
+				AWS: &AWS{
+					Region:              "us-east-1",
+					Role:                "arn:aws:iam::123456123456:role/test",
+					RoleSessionName:     "vela",
+					RoleDurationSeconds: 43200,
+					DurationFallback:    tt.fallback,
+					DurationLadder:      []int{43200, 21600, 3600, 900},
+				},
+				Logger: logrus.NewEntry(logrus.StandardLogger()),
+			}
+
+			creds, err := c.AssumeRole(context.Background(), "token")
+			assert.Equal(t, tt.wantDuration, got)
+
+			if tt.wantErr {
+				assert.Error(t, err, "An error was expected")
+				assert.True(t, isDurationTooLongError(err))
+
+				return
+			}
+
+			assert.NoError(t, err, "No error was expected")
+			assert.Equal(t, "ACCESS_KEY_ID", creds.AccessKeyID)
+			assert.True(t, creds.CanExpire)
+			assert.Equal(t, "arn:aws:sts::123456123456:assumed-role/test/vela", c.Session.AssumedRoleARN)
+			assert.Equal(t, "123456123456", c.Session.AccountID)
+			assert.Equal(t, tt.wantDuration[len(tt.wantDuration)-1], strconv.Itoa(c.Session.DurationSeconds))
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxRoleSessionNameLength represents the maximum length of an STS role session name.
+const maxRoleSessionNameLength = 64
+
+// invalidRoleSessionNameChars matches the characters STS does not accept in a role session name.
+var invalidRoleSessionNameChars = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// BuildContext represents the Vela build the plugin runs in, as read from
+// the standard Vela environment.
+type BuildContext struct {
+	Org         string
+	Repo        string
+	BuildNumber int
+	Branch      string
+	Event       string
+	Commit      string
+	Author      string
+	Tag         string
+	Target      string
+	Workspace   string
+	Link        string
+}
+
+// Fields returns the build context as log fields for auditing.
+func (b *BuildContext) Fields() logrus.Fields {
+	if b == nil {
+		return logrus.Fields{}
+	}
+
+	return logrus.Fields{
+		"org":          b.Org,
+		"repo":         b.Repo,
+		"build_number": b.BuildNumber,
+		"branch":       b.Branch,
+		"event":        b.Event,
+		"commit":       b.Commit,
+		"author":       b.Author,
+		"tag":          b.Tag,
+		"target":       b.Target,
+		"build_link":   b.Link,
+	}
+}
+
+// sanitizeRoleSessionName replaces the characters STS does not accept in a
+// role session name and truncates it to 64 characters.
+func sanitizeRoleSessionName(name string) string {
+	name = invalidRoleSessionNameChars.ReplaceAllString(name, "-")
+	if len(name) > maxRoleSessionNameLength {
+		name = name[:maxRoleSessionNameLength]
+	}
+
+	return name
+}
+
+// loadRoleSessionName renders the role session name with the build context,
+// replacing characters STS does not accept and truncating it to 64 characters.
+func (c *Config) loadRoleSessionName() error {
+	if c.AWS.RoleSessionName == "" {
+		c.AWS.RoleSessionName = "vela"
+	}
+
+	name, err := c.renderTemplate("role session name", c.AWS.RoleSessionName)
+	if err != nil {
+		return err
+	}
+
+	name = sanitizeRoleSessionName(name)
+
+	if len(name) < 2 {
+		return fmt.Errorf("role session name %q must be at least 2 characters", name)
+	}
+
+	c.AWS.RoleSessionName = name
+
+	return nil
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_loadRoleSessionName(t *testing.T) {
+	build := &BuildContext{
+		Org:         "octo-org",
+		Repo:        "octo-repo",
+		BuildNumber: 42,
+		Branch:      "feature/login",
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "default",
+			text: "",
+			want: "vela",
+		},
+		{
+			name: "build context",
+			text: "vela-{{ .Repo }}-{{ .BuildNumber }}",
+			want: "vela-octo-repo-42",
+		},
+		{
+			name: "invalid characters replaced",
+			text: "{{ .Org }}/{{ .Branch }}",
+			want: "octo-org-feature-login",
+		},
+		{
+			name: "truncated",
+			text: strings.Repeat("a", 80),
+			want: strings.Repeat("a", maxRoleSessionNameLength),
+		},
+		{
+			name:    "too short",
+			text:    "x",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{
+				AWS:   &AWS{RoleSessionName: tt.text},
+				Build: build,
+			}
+
+			err := c.loadRoleSessionName()
+			if tt.wantErr {
+				assert.Error(t, err, "An error was expected")
+				return
+			}
+
+			assert.NoError(t, err, "No error was expected")
+			assert.Equal(t, tt.want, c.AWS.RoleSessionName)
+		})
+	}
+}
@@ -0,0 +1,508 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// revokePolicyPrefix represents the prefix of the inline policies that revoke
+// the sessions of a build, followed by the time the last of them expires and a
+// hash of the sessions.
+const revokePolicyPrefix = "VelaRevokeSessions-"
+
+type (
+	// Manifest represents the files written and the sessions issued by earlier
+	// runs of the plugin in the build, so the cleanup action can remove them.
+	Manifest struct {
+		Sessions []ManifestSession `json:"sessions,omitempty"`
+		Files    []string          `json:"files"`
+		// DockerAuths are the registries logged in to, by Docker config file.
+		DockerAuths map[string][]string `json:"docker_auths,omitempty"`
+	}
+
+	// ManifestSession represents a session issued by the plugin.
+	ManifestSession struct {
+		Role string `json:"role"`
+		// UserID is the aws:userid of the session, the role ID and session name.
+		UserID     string    `json:"user_id"`
+		Expiration time.Time `json:"expiration"`
+	}
+)
+
+// readManifest reads the manifest at path, returning an empty manifest when it does not exist.
+func readManifest(path string) (*Manifest, error) {
+	manifest := new(Manifest)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read manifest: %w", err)
+	}
+
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse manifest %s: %w", path, err)
+	}
+
+	return manifest, nil
+}
+
+// trackFile records a file written by the plugin for the cleanup action and
+// adds it to the manifest right away, so it is cleaned up even if a later
+// step fails. Exec writes the manifest again when it returns and reports a
+// failure to write it.
+func (c *Config) trackFile(path string) {
+	if slices.Contains(c.writtenFiles, path) {
+		return
+	}
+
+	c.writtenFiles = append(c.writtenFiles, path)
+
+	err := c.WriteManifest()
+	if err != nil {
+		c.Logger.Warnf("unable to record %s in the manifest: %v", path, err)
+	}
+}
+
+// trackDockerAuths records the registries logged in to in the Docker config
+// file at path and updates the manifest right away, like trackFile.
+func (c *Config) trackDockerAuths(path string, hosts []string) {
+	if c.dockerAuths == nil {
+		c.dockerAuths = map[string][]string{}
+	}
+
+	for _, host := range hosts {
+		if !slices.Contains(c.dockerAuths[path], host) {
+			c.dockerAuths[path] = append(c.dockerAuths[path], host)
+		}
+	}
+
+	err := c.WriteManifest()
+	if err != nil {
+		c.Logger.Warnf("unable to record the registries of %s in the manifest: %v", path, err)
+	}
+}
+
+// WriteManifest adds the session issued and the files written by the plugin
+// to the manifest, keeping the entries of earlier runs in the build. The
+// session is recorded even when no file is written, so the cleanup action
+// can revoke it.
+func (c *Config) WriteManifest() error {
+	issued := c.Session != nil && c.Session.AssumedRoleID != ""
+
+	if c.ManifestPath == "" || (!issued && len(c.writtenFiles) == 0 && len(c.dockerAuths) == 0) {
+		return nil
+	}
+
+	manifest, err := readManifest(c.ManifestPath)
+	if err != nil {
+		return err
+	}
+
+	if issued {
+		session := ManifestSession{Role: c.AWS.Role, UserID: c.Session.AssumedRoleID, Expiration: c.Session.Expiration.UTC()}
+		if !slices.Contains(manifest.Sessions, session) {
+			manifest.Sessions = append(manifest.Sessions, session)
+		}
+	}
+
+	for _, file := range c.writtenFiles {
+		if !slices.Contains(manifest.Files, file) {
+			manifest.Files = append(manifest.Files, file)
+		}
+	}
+
+	for path, hosts := range c.dockerAuths {
+		if manifest.DockerAuths == nil {
+			manifest.DockerAuths = map[string][]string{}
+		}
+
+		for _, host := range hosts {
+			if !slices.Contains(manifest.DockerAuths[path], host) {
+				manifest.DockerAuths[path] = append(manifest.DockerAuths[path], host)
+			}
+		}
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(c.ManifestPath), 0700)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.ManifestPath, data, 0600)
+}
+
+// Cleanup overwrites and deletes every file listed in the manifest and removes
+// the registry logins from the Docker config files. When a revoke role is
+// configured, it also revokes the sessions in the manifest, even when a file
+// could not be removed. The manifest is only removed once every step
+// succeeded, so the cleanup can be retried.
+func (c *Config) Cleanup(ctx context.Context) error {
+	manifest, err := readManifest(c.ManifestPath)
+	if err != nil {
+		return newError(ErrorKindOutput, err)
+	}
+
+	var errs []error
+
+	for _, file := range manifest.Files {
+		err := shred(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to delete %s: %w", file, err))
+
+			continue
+		}
+
+		c.Logger.Infof("deleted %s", file)
+	}
+
+	// Docker config files may hold the user's own settings and registries, so
+	// only the logins of the plugin are removed
+	for _, path := range sortedKeys(manifest.DockerAuths) {
+		err := removeDockerAuths(path, manifest.DockerAuths[path])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to remove the registry logins from %s: %w", path, err))
+
+			continue
+		}
+
+		c.Logger.Infof("removed the logins of %s from %s", strings.Join(manifest.DockerAuths[path], ", "), path)
+	}
+
+	fileErr := newError(ErrorKindOutput, errors.Join(errs...))
+
+	var revokeErr error
+	if c.AWS.RevokeRole != "" && len(manifest.Sessions) > 0 {
+		revokeErr = c.revokeRecordedSessions(ctx, manifest.Sessions)
+	}
+
+	if fileErr != nil || revokeErr != nil {
+		return errors.Join(fileErr, revokeErr)
+	}
+
+	err = os.Remove(c.ManifestPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return newError(ErrorKindOutput, fmt.Errorf("unable to delete manifest: %w", err))
+	}
+
+	return nil
+}
+
+// revokeRecordedSessions requests an ID token and revokes the sessions of the
+// role of the step once the allowlists allow it. The manifest is writable by
+// the pipeline, so sessions of other roles are skipped.
+func (c *Config) revokeRecordedSessions(ctx context.Context, sessions []ManifestSession) error {
+	var owned []ManifestSession
+
+	for _, session := range sessions {
+		if session.Role != c.AWS.Role {
+			c.Logger.Warnf("skipping revocation of session %s: role %s is not the role %s of the step", session.UserID, session.Role, c.AWS.Role)
+
+			continue
+		}
+
+		owned = append(owned, session)
+	}
+
+	if len(owned) == 0 {
+		return nil
+	}
+
+	var errs []error
+
+	for _, session := range owned {
+		if !c.buildUniqueSession(session) {
+			errs = append(errs, fmt.Errorf("refusing to revoke session %s: the session name does not include the build number %d, "+
+				"so concurrent builds would be revoked too; set role_session_name to e.g. vela-{{ .Repo }}-{{ .BuildNumber }}",
+				session.UserID, c.Build.BuildNumber))
+		}
+	}
+
+	if len(errs) > 0 {
+		return newError(ErrorKindConfig, errors.Join(errs...))
+	}
+
+	token, err := c.requestIDToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = c.enforceRevokeAllowlists(token)
+	if err != nil {
+		return err
+	}
+
+	return c.revokeSessions(ctx, token, owned)
+}
+
+// shred overwrites the file with zeros before deleting it. Files that no
+// longer exist are ignored.
+func shred(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+
+		return err
+	}
+
+	_, err = io.CopyN(f, zeroReader{}, info.Size())
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// zeroReader is an io.Reader that reads zeros.
+type zeroReader struct{}
+
+// Read fills p with zeros.
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+
+	return len(p), nil
+}
+
+// buildUniqueSession reports whether the session name of the session holds the
+// build number as a separate word, so the revoke policy only matches the
+// sessions of this build.
+func (c *Config) buildUniqueSession(session ManifestSession) bool {
+	_, name, ok := strings.Cut(session.UserID, ":")
+	if !ok || c.Build.BuildNumber == 0 {
+		return false
+	}
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return slices.Contains(words, strconv.Itoa(c.Build.BuildNumber))
+}
+
+// revokeSessions assumes the revoke role with the ID token and attaches a
+// policy to the role of each session denying that session, identified by its
+// aws:userid, every action. Sessions of other builds with a different session
+// name are not affected. Revoke policies of sessions that have since expired
+// are removed.
+func (c *Config) revokeSessions(ctx context.Context, token string, sessions []ManifestSession) error {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(c.AWS.Region))
+	if err != nil {
+		return newError(ErrorKindAssumeRole, err)
+	}
+
+	var output *sts.AssumeRoleWithWebIdentityOutput
+
+	err = c.runPhase(ctx, phaseAssumeRole, c.Timeout.AssumeRole, func(ctx context.Context) error {
+		var err error
+
+		output, err = c.assumeRoleWithWebIdentity(ctx, sts.NewFromConfig(cfg), &sts.AssumeRoleWithWebIdentityInput{
+			RoleArn:          aws.String(c.AWS.RevokeRole),
+			RoleSessionName:  aws.String(c.AWS.RoleSessionName),
+			WebIdentityToken: aws.String(token),
+			DurationSeconds:  aws.Int32(minRoleDurationSeconds),
+		})
+
+		return err
+	})
+	if err != nil {
+		return classifySTSError(fmt.Errorf("failed to assume revoke role: %w", err))
+	}
+
+	c.redact(aws.ToString(output.Credentials.AccessKeyId), aws.ToString(output.Credentials.SecretAccessKey), aws.ToString(output.Credentials.SessionToken))
+
+	cfg.Credentials = credentials.NewStaticCredentialsProvider(
+		aws.ToString(output.Credentials.AccessKeyId),
+		aws.ToString(output.Credentials.SecretAccessKey),
+		aws.ToString(output.Credentials.SessionToken),
+	)
+
+	client := iam.NewFromConfig(cfg)
+	now := time.Now()
+
+	byRole := map[string][]ManifestSession{}
+	for _, session := range sessions {
+		byRole[session.Role] = append(byRole[session.Role], session)
+	}
+
+	err = c.runPhase(ctx, phaseRevoke, c.Timeout.Revoke, func(ctx context.Context) error {
+		var errs []error
+
+		for _, arn := range sortedKeys(byRole) {
+			role, err := ParseRoleARN(arn)
+			if err != nil {
+				errs = append(errs, err)
+
+				continue
+			}
+
+			c.removeExpiredRevokePolicies(ctx, client, role.Name, now)
+
+			name, policy := revokePolicy(byRole[arn], now)
+
+			err = c.Retry.Do(ctx, c.Logger, "IAM PutRolePolicy", isRetryableIAMError, func(ctx context.Context) error {
+				_, err := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+					RoleName:       aws.String(role.Name),
+					PolicyName:     aws.String(name),
+					PolicyDocument: aws.String(policy),
+				})
+
+				return err
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to revoke sessions of %s: %w", arn, err))
+
+				continue
+			}
+
+			c.Logger.Infof("revoked %d session(s) of %s with the inline policy %s", len(byRole[arn]), arn, name)
+		}
+
+		return errors.Join(errs...)
+	})
+
+	return newError(ErrorKindAssumeRole, err)
+}
+
+// removeExpiredRevokePolicies deletes the revoke policies of the role whose
+// sessions have all expired. Failures are only logged, since they do not
+// affect the revocation.
+func (c *Config) removeExpiredRevokePolicies(ctx context.Context, client *iam.Client, role string, now time.Time) {
+	var output *iam.ListRolePoliciesOutput
+
+	err := c.Retry.Do(ctx, c.Logger, "IAM ListRolePolicies", isRetryableIAMError, func(ctx context.Context) error {
+		var err error
+
+		output, err = client.ListRolePolicies(ctx, &iam.ListRolePoliciesInput{RoleName: aws.String(role)})
+
+		return err
+	})
+	if err != nil {
+		c.Logger.Warnf("unable to list the inline policies of role %s: %v", role, err)
+
+		return
+	}
+
+	for _, name := range output.PolicyNames {
+		expiry, ok := revokePolicyExpiry(name)
+		if !ok || expiry.After(now) {
+			continue
+		}
+
+		err = c.Retry.Do(ctx, c.Logger, "IAM DeleteRolePolicy", isRetryableIAMError, func(ctx context.Context) error {
+			_, err := client.DeleteRolePolicy(ctx, &iam.DeleteRolePolicyInput{RoleName: aws.String(role), PolicyName: aws.String(name)})
+
+			return err
+		})
+		if err != nil {
+			c.Logger.Warnf("unable to delete the expired inline policy %s of role %s: %v", name, role, err)
+
+			continue
+		}
+
+		c.Logger.Infof("deleted the expired inline policy %s of role %s", name, role)
+	}
+}
+
+// revokePolicy returns the name and document of a policy denying every action
+// to the sessions issued before t. The name holds the time the last session
+// expires, after which the policy may be removed.
+func revokePolicy(sessions []ManifestSession, t time.Time) (string, string) {
+	var (
+		userIDs StringList
+		expiry  time.Time
+	)
+
+	for _, session := range sessions {
+		userIDs = append(userIDs, session.UserID)
+
+		if session.Expiration.After(expiry) {
+			expiry = session.Expiration
+		}
+	}
+
+	// sessions without a known expiration last at most 12 hours
+	if expiry.IsZero() {
+		expiry = t.Add(maxRoleDurationSeconds * time.Second)
+	}
+
+	policy := &PolicyDocument{
+		Version: "2012-10-17",
+		Statement: PolicyStatements{{
+			Effect:   "Deny",
+			Action:   StringList{"*"},
+			Resource: StringList{"*"},
+			Condition: map[string]map[string]StringList{
+				"StringEquals": {"aws:userid": userIDs},
+				"DateLessThan": {"aws:TokenIssueTime": {t.UTC().Format(time.RFC3339)}},
+			},
+		}},
+	}
+
+	data, _ := json.Marshal(policy)
+
+	hash := sha256.Sum256([]byte(strings.Join(userIDs, ",")))
+
+	return fmt.Sprintf("%s%d-%x", revokePolicyPrefix, expiry.Unix(), hash[:6]), string(data)
+}
+
+// revokePolicyExpiry returns the time the sessions of a revoke policy expire,
+// reporting whether name is the name of a revoke policy.
+func revokePolicyExpiry(name string) (time.Time, bool) {
+	rest, ok := strings.CutPrefix(name, revokePolicyPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	seconds, _, _ := strings.Cut(rest, "-")
+
+	unix, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(unix, 0), true
+}
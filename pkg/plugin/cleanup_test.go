@@ -0,0 +1,452 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_WriteManifest(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "aws", "manifest.json")
+
+	first := &Config{
+		ManifestPath: manifestPath,
+		AWS:          &AWS{Role: "arn:aws:iam::123456123456:role/build"},
+	}
+	first.trackFile(filepath.Join(dir, "setup.sh"))
+
+	second := &Config{
+		ManifestPath: manifestPath,
+		AWS:          &AWS{Role: "arn:aws:iam::123456123456:role/deploy"},
+	}
+	second.Session = &Session{AssumedRoleID: "AROAEXAMPLE:vela-42", Expiration: time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)}
+	second.trackFile(filepath.Join(dir, "setup.sh"))
+	second.trackFile(filepath.Join(dir, "creds"))
+
+	assert.NoError(t, first.WriteManifest())
+	assert.NoError(t, second.WriteManifest())
+
+	manifest, err := readManifest(manifestPath)
+	assert.NoError(t, err)
+	assert.Equal(t, &Manifest{
+		Sessions: []ManifestSession{
+			{Role: "arn:aws:iam::123456123456:role/deploy", UserID: "AROAEXAMPLE:vela-42", Expiration: time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)},
+		},
+		Files: []string{filepath.Join(dir, "setup.sh"), filepath.Join(dir, "creds")},
+	}, manifest)
+
+	info, err := os.Stat(manifestPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestConfig_WriteManifest_NoFiles(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+
+	c := &Config{ManifestPath: manifestPath, AWS: &AWS{}}
+
+	assert.NoError(t, c.WriteManifest())
+	assert.NoFileExists(t, manifestPath)
+}
+
+func TestConfig_WriteManifest_SessionOnly(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+
+	c := &Config{ManifestPath: manifestPath, AWS: &AWS{Role: "arn:aws:iam::123456123456:role/test"}}
+	c.Session = &Session{AssumedRoleID: "AROAEXAMPLE:vela-42", Expiration: time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)}
+
+	assert.NoError(t, c.WriteManifest())
+
+	manifest, err := readManifest(manifestPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []ManifestSession{
+		{Role: "arn:aws:iam::123456123456:role/test", UserID: "AROAEXAMPLE:vela-42", Expiration: time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)},
+	}, manifest.Sessions)
+	assert.Empty(t, manifest.Files)
+}
+
+func TestConfig_deliverCredentials_ManifestOnFailure(t *testing.T) {
+	newTestAWS(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+
+		if strings.HasSuffix(r.Header.Get("X-Amz-Target"), ".GetSecretValue") {
+			fmt.Fprint(w, `{"Name": "db", "SecretString": "secret"}`)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"__type": "AccessDeniedException", "message": "not authorized to perform ecr:GetAuthorizationToken"}`)
+	})
+
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "secrets.env")
+	manifestPath := filepath.Join(dir, "manifest.json")
+
+	c := &Config{
+		ManifestPath:   manifestPath,
+		SecretsEnvFile: envFile,
+		DockerConfig:   filepath.Join(dir, "docker", "config.json"),
+		AWS:            &AWS{Region: "us-east-1", Role: "arn:aws:iam::123456123456:role/build"},
+		Redactor:       NewRedactor(),
+		Logger:         logrus.NewEntry(logrus.StandardLogger()),
+		secrets:        []Secret{{Name: "DB_PASSWORD", SecretID: "db"}},
+		ecrRegistries:  []ECRRegistry{{Host: "123456123456.dkr.ecr.us-east-1.amazonaws.com", Region: "us-east-1"}},
+		Timeout:        &Timeout{},
+	}
+
+	err := c.deliverCredentials(context.Background(), &aws.Credentials{AccessKeyID: "ACCESS_KEY_ID", SecretAccessKey: "SECRET_ACCESS_KEY"})
+	assert.ErrorContains(t, err, "unable to get ECR authorization token")
+	assert.Equal(t, ErrorKindLogin.ExitCode(), ExitCode(err))
+
+	// the secrets written before the ECR login failed are recorded for cleanup
+	manifest, err := readManifest(manifestPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{envFile}, manifest.Files)
+}
+
+func TestConfig_Cleanup(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "manifest.json")
+	script := filepath.Join(dir, "setup.sh")
+	creds := filepath.Join(dir, "creds")
+
+	for _, file := range []string{script, creds} {
+		err := os.WriteFile(file, []byte("export AWS_SECRET_ACCESS_KEY=SECRET_ACCESS_KEY"), 0600)
+		if err != nil {
+			t.Fatalf("unable to write %s: %v", file, err)
+		}
+	}
+
+	data, err := json.Marshal(&Manifest{
+		// the missing file was already removed by the pipeline
+		Files: []string{script, creds, filepath.Join(dir, "missing")},
+	})
+	if err != nil {
+		t.Fatalf("unable to encode manifest: %v", err)
+	}
+
+	err = os.WriteFile(manifestPath, data, 0600)
+	if err != nil {
+		t.Fatalf("unable to write manifest: %v", err)
+	}
+
+	c := &Config{
+		Action:       ActionCleanup,
+		ManifestPath: manifestPath,
+		AWS:          &AWS{},
+		Logger:       logrus.NewEntry(logrus.StandardLogger()),
+	}
+
+	assert.NoError(t, c.Cleanup(context.Background()))
+
+	for _, file := range []string{script, creds, manifestPath} {
+		assert.NoFileExists(t, file)
+	}
+}
+
+func TestConfig_Cleanup_RevokeFailure(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "manifest.json")
+	creds := filepath.Join(dir, "creds")
+
+	err := os.WriteFile(creds, []byte("SECRET_ACCESS_KEY"), 0600)
+	if err != nil {
+		t.Fatalf("unable to write %s: %v", creds, err)
+	}
+
+	data, err := json.Marshal(&Manifest{
+		Sessions: []ManifestSession{{Role: "arn:aws:iam::123456123456:role/test", UserID: "AROAEXAMPLE:vela-42"}},
+		Files:    []string{creds},
+	})
+	if err != nil {
+		t.Fatalf("unable to encode manifest: %v", err)
+	}
+
+	err = os.WriteFile(manifestPath, data, 0600)
+	if err != nil {
+		t.Fatalf("unable to write manifest: %v", err)
+	}
+
+	c := &Config{
+		Action:       ActionCleanup,
+		ManifestPath: manifestPath,
+		AWS:          &AWS{Role: "arn:aws:iam::123456123456:role/test", RevokeRole: "arn:aws:iam::123456123456:role/revoke"},
+		Build:        &BuildContext{BuildNumber: 42},
+		Vela:         &Vela{RequestTokenURL: "://invalid"},
+		Timeout:      &Timeout{},
+		Logger:       logrus.NewEntry(logrus.StandardLogger()),
+	}
+
+	err = c.Cleanup(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, ErrorKindToken.ExitCode(), ExitCode(err))
+
+	// the files are removed, but the manifest is kept so the revocation can
+	// be retried
+	assert.NoFileExists(t, creds)
+	assert.FileExists(t, manifestPath)
+}
+
+func TestConfig_Cleanup_OtherRole(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+
+	data, err := json.Marshal(&Manifest{
+		Sessions: []ManifestSession{{Role: "arn:aws:iam::123456123456:role/other-team", UserID: "AROAEXAMPLE:vela-42"}},
+	})
+	if err != nil {
+		t.Fatalf("unable to encode manifest: %v", err)
+	}
+
+	err = os.WriteFile(manifestPath, data, 0600)
+	if err != nil {
+		t.Fatalf("unable to write manifest: %v", err)
+	}
+
+	// the request token url is invalid, so requesting a token would fail
+	c := &Config{
+		Action:       ActionCleanup,
+		ManifestPath: manifestPath,
+		AWS:          &AWS{Role: "arn:aws:iam::123456123456:role/test", RevokeRole: "arn:aws:iam::123456123456:role/revoke"},
+		Build:        &BuildContext{BuildNumber: 42},
+		Vela:         &Vela{RequestTokenURL: "://invalid"},
+		Timeout:      &Timeout{},
+		Logger:       logrus.NewEntry(logrus.StandardLogger()),
+	}
+
+	// sessions of other roles in the pipeline-writable manifest are skipped
+	assert.NoError(t, c.Cleanup(context.Background()))
+	assert.NoFileExists(t, manifestPath)
+}
+
+func TestConfig_Cleanup_DockerConfig(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "manifest.json")
+	shared := filepath.Join(dir, "shared", "config.json")
+	owned := filepath.Join(dir, "owned", "config.json")
+
+	const ecr = "123456123456.dkr.ecr.us-east-1.amazonaws.com"
+
+	// the user's own config holds other settings and registries
+	err := writeSecretFile(shared, `{"credsStore": "desktop", "auths": {"ghcr.io": {"auth": "Z2hjcg=="}}}`)
+	if err != nil {
+		t.Fatalf("unable to write Docker config: %v", err)
+	}
+
+	c := &Config{
+		ManifestPath: manifestPath,
+		AWS:          &AWS{},
+		Logger:       logrus.NewEntry(logrus.StandardLogger()),
+	}
+
+	for _, path := range []string{shared, owned} {
+		err = writeDockerConfig(path, map[string]string{ecr: "QVdTOnBhc3N3b3Jk"})
+		if err != nil {
+			t.Fatalf("unable to write Docker config: %v", err)
+		}
+
+		c.trackDockerAuths(path, []string{ecr})
+	}
+
+	c.Action = ActionCleanup
+
+	assert.NoError(t, c.Cleanup(context.Background()))
+
+	data, err := os.ReadFile(shared)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"credsStore": "desktop", "auths": {"ghcr.io": {"auth": "Z2hjcg=="}}}`, string(data))
+
+	// a config left empty is deleted
+	assert.NoFileExists(t, owned)
+	assert.NoFileExists(t, manifestPath)
+}
+
+func TestConfig_Cleanup_NoManifest(t *testing.T) {
+	c := &Config{
+		ManifestPath: filepath.Join(t.TempDir(), "manifest.json"),
+		AWS:          &AWS{RevokeRole: "arn:aws:iam::123456123456:role/revoke"},
+		Logger:       logrus.NewEntry(logrus.StandardLogger()),
+	}
+
+	assert.NoError(t, c.Cleanup(context.Background()))
+}
+
+func TestShred(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds")
+
+	err := os.WriteFile(path, []byte("SECRET_ACCESS_KEY"), 0600)
+	if err != nil {
+		t.Fatalf("unable to write file: %v", err)
+	}
+
+	// keep a handle open to observe the contents after the file is removed
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unable to open file: %v", err)
+	}
+	defer f.Close()
+
+	assert.NoError(t, shred(path))
+	assert.NoFileExists(t, path)
+
+	buf := make([]byte, len("SECRET_ACCESS_KEY"))
+
+	_, err = f.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, make([]byte, len(buf)), buf)
+}
+
+func TestConfig_revokeSessions(t *testing.T) {
+	expired := revokePolicyPrefix + "1700000000-0123456789ab"
+	pending := revokePolicyPrefix + "4102444800-0123456789ab"
+
+	var (
+		revoked  []string
+		policies []string
+		deleted  []string
+	)
+
+	newTestAWS(t, func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			t.Errorf("unable to parse request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "text/xml")
+
+		switch r.PostForm.Get("Action") {
+		case "AssumeRoleWithWebIdentity":
+			assert.Equal(t, "arn:aws:iam::123456123456:role/revoke", r.PostForm.Get("RoleArn"))
+			assert.Equal(t, "900", r.PostForm.Get("DurationSeconds"))
+
+			_, _ = w.Write([]byte(stsCredentialsResponse))
+		case "ListRolePolicies":
+			fmt.Fprintf(w, `<ListRolePoliciesResponse xmlns="https://iam.amazonaws.com/doc/2010-05-08/"><ListRolePoliciesResult>`+
+				`<PolicyNames><member>%s</member><member>%s</member><member>AWSRevokeOlderSessions</member></PolicyNames>`+
+				`</ListRolePoliciesResult></ListRolePoliciesResponse>`, expired, pending)
+		case "DeleteRolePolicy":
+			deleted = append(deleted, r.PostForm.Get("RoleName")+"/"+r.PostForm.Get("PolicyName"))
+
+			_, _ = w.Write([]byte(`<DeleteRolePolicyResponse xmlns="https://iam.amazonaws.com/doc/2010-05-08/"></DeleteRolePolicyResponse>`))
+		case "PutRolePolicy":
+			revoked = append(revoked, r.PostForm.Get("RoleName"))
+			policies = append(policies, r.PostForm.Get("PolicyDocument"))
+
+			assert.True(t, strings.HasPrefix(r.PostForm.Get("PolicyName"), revokePolicyPrefix))
+
+			_, _ = w.Write([]byte(`<PutRolePolicyResponse xmlns="https://iam.amazonaws.com/doc/2010-05-08/"></PutRolePolicyResponse>`))
+		default:
+			t.Errorf("unexpected request %s", r.PostForm.Get("Action"))
+		}
+	})
+
+	c := &Config{
+		AWS: &AWS{
+			Region:          "us-east-1",
+			RevokeRole:      "arn:aws:iam::123456123456:role/revoke",
+			RoleSessionName: "vela",
+		},
+		Timeout:  &Timeout{},
+		Redactor: NewRedactor(),
+		Logger:   logrus.NewEntry(logrus.StandardLogger()),
+	}
+
+	err := c.revokeSessions(context.Background(), "token", []ManifestSession{
+		{Role: "arn:aws:iam::123456123456:role/build", UserID: "AROAEXAMPLE1:vela-42"},
+		{Role: "arn:aws:iam::123456123456:role/path/deploy", UserID: "AROAEXAMPLE2:vela-42"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"build", "deploy"}, revoked)
+
+	// only the sessions of the build are denied
+	assert.Contains(t, policies[0], `"aws:userid":"AROAEXAMPLE1:vela-42"`)
+	assert.Contains(t, policies[1], `"aws:userid":"AROAEXAMPLE2:vela-42"`)
+
+	// only revoke policies that have expired are removed
+	assert.Equal(t, []string{"build/" + expired, "deploy/" + expired}, deleted)
+}
+
+func TestConfig_buildUniqueSession(t *testing.T) {
+	c := &Config{Build: &BuildContext{BuildNumber: 42}}
+
+	tests := []struct {
+		userID string
+		want   bool
+	}{
+		{"AROAEXAMPLE:vela-octo-repo-42", true},
+		{"AROAEXAMPLE:42", true},
+		{"AROAEXAMPLE:vela", false},
+		{"AROAEXAMPLE:vela-142", false},
+		{"AROAEXAMPLE", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			assert.Equal(t, tt.want, c.buildUniqueSession(ManifestSession{UserID: tt.userID}))
+		})
+	}
+}
+
+func TestConfig_Cleanup_SharedSessionName(t *testing.T) {
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+
+	data, err := json.Marshal(&Manifest{
+		Sessions: []ManifestSession{{Role: "arn:aws:iam::123456123456:role/test", UserID: "AROAEXAMPLE:vela"}},
+	})
+	if err != nil {
+		t.Fatalf("unable to encode manifest: %v", err)
+	}
+
+	err = os.WriteFile(manifestPath, data, 0600)
+	if err != nil {
+		t.Fatalf("unable to write manifest: %v", err)
+	}
+
+	c := &Config{
+		Action:       ActionCleanup,
+		ManifestPath: manifestPath,
+		AWS:          &AWS{Role: "arn:aws:iam::123456123456:role/test", RevokeRole: "arn:aws:iam::123456123456:role/revoke"},
+		Build:        &BuildContext{BuildNumber: 42},
+		Timeout:      &Timeout{},
+		Logger:       logrus.NewEntry(logrus.StandardLogger()),
+	}
+
+	// the session name is shared by every build of the role
+	err = c.Cleanup(context.Background())
+	assert.Equal(t, ErrorKindConfig.ExitCode(), ExitCode(err))
+	assert.ErrorContains(t, err, "does not include the build number 42")
+	assert.FileExists(t, manifestPath)
+}
+
+func TestRevokePolicy(t *testing.T) {
+	issued := time.Date(2030, 1, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	name, got := revokePolicy([]ManifestSession{
+		{UserID: "AROAEXAMPLE:vela-42", Expiration: issued.Add(time.Hour)},
+		{UserID: "AROAEXAMPLE:vela-42-deploy", Expiration: issued.Add(2 * time.Hour)},
+	}, issued)
+
+	want := `{"Version":"2012-10-17","Statement":[{"Effect":"Deny","Action":"*","Resource":"*",` +
+		`"Condition":{"DateLessThan":{"aws:TokenIssueTime":"2030-01-01T11:00:00Z"},` +
+		`"StringEquals":{"aws:userid":["AROAEXAMPLE:vela-42","AROAEXAMPLE:vela-42-deploy"]}}}]}`
+
+	assert.Equal(t, want, got)
+
+	expiry, ok := revokePolicyExpiry(name)
+	assert.True(t, ok)
+	assert.Equal(t, issued.Add(2*time.Hour).Unix(), expiry.Unix())
+	assert.LessOrEqual(t, len(name), 128)
+}
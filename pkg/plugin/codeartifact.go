@@ -0,0 +1,358 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/codeartifact"
+	"github.com/aws/aws-sdk-go-v2/service/codeartifact/types"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// codeArtifactUsername represents the username CodeArtifact expects with its authorization token.
+	codeArtifactUsername = "aws"
+
+	// codeArtifactMavenProfile represents the id of the Maven profile holding the CodeArtifact repositories.
+	codeArtifactMavenProfile = "codeartifact"
+)
+
+// codeArtifactFormats represents the package formats configuration files are written for.
+var codeArtifactFormats = []string{string(types.PackageFormatNpm), string(types.PackageFormatPypi), string(types.PackageFormatMaven)}
+
+type (
+	// CodeArtifact represents a CodeArtifact domain to log in to and the
+	// repositories to configure the package managers for.
+	CodeArtifact struct {
+		Domain          string                   `yaml:"domain"`
+		DomainOwner     string                   `yaml:"domain_owner"`
+		DurationSeconds int64                    `yaml:"duration_seconds"`
+		Repositories    []CodeArtifactRepository `yaml:"repositories"`
+	}
+
+	// CodeArtifactRepository represents a CodeArtifact repository of a package format.
+	CodeArtifactRepository struct {
+		Repository string `yaml:"repository"`
+		Format     string `yaml:"format"`
+		// Scope is the npm scope served by the repository. The npm repository
+		// without a scope becomes the default registry.
+		Scope string `yaml:"scope"`
+
+		// endpoint is the URL of the repository for its format
+		endpoint string
+	}
+)
+
+// ParseCodeArtifact parses the CodeArtifact configuration in YAML or JSON, rejecting unknown keys.
+func ParseCodeArtifact(data string) (*CodeArtifact, error) {
+	ca := &CodeArtifact{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(ca)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse codeartifact: %w", err)
+	}
+
+	var errs []error
+
+	if ca.Domain == "" {
+		errs = append(errs, fmt.Errorf("no codeartifact domain provided"))
+	}
+
+	if ca.DomainOwner != "" && !accountIDPattern.MatchString(ca.DomainOwner) {
+		errs = append(errs, fmt.Errorf("codeartifact domain_owner %q must be an account ID", ca.DomainOwner))
+	}
+
+	if ca.DurationSeconds != 0 && (ca.DurationSeconds < 900 || ca.DurationSeconds > 43200) {
+		errs = append(errs, fmt.Errorf("codeartifact duration_seconds must be 0 or between 900 and 43200"))
+	}
+
+	if len(ca.Repositories) == 0 {
+		errs = append(errs, fmt.Errorf("no codeartifact repositories provided"))
+	}
+
+	unscoped := 0
+	scopes := map[string]bool{}
+
+	for i, repo := range ca.Repositories {
+		if repo.Repository == "" {
+			errs = append(errs, fmt.Errorf("codeartifact repository #%d has no repository", i))
+		}
+
+		if !slices.Contains(codeArtifactFormats, repo.Format) {
+			errs = append(errs, fmt.Errorf("codeartifact repository %s: only formats of %s are supported", repo.Repository, codeArtifactFormats))
+		}
+
+		switch {
+		case repo.Scope != "" && repo.Format != string(types.PackageFormatNpm):
+			errs = append(errs, fmt.Errorf("codeartifact repository %s: scope only applies to npm", repo.Repository))
+		case repo.Scope != "" && !strings.HasPrefix(repo.Scope, "@"):
+			errs = append(errs, fmt.Errorf("codeartifact repository %s: scope %q must start with @", repo.Repository, repo.Scope))
+		case scopes[repo.Scope]:
+			errs = append(errs, fmt.Errorf("codeartifact repository %s: scope %s is served by another repository", repo.Repository, repo.Scope))
+		case repo.Scope == "" && repo.Format == string(types.PackageFormatNpm):
+			unscoped++
+		}
+
+		if repo.Scope != "" {
+			scopes[repo.Scope] = true
+		}
+	}
+
+	// npm has a single default registry
+	if unscoped > 1 {
+		errs = append(errs, fmt.Errorf("only one npm codeartifact repository may have no scope"))
+	}
+
+	return ca, errors.Join(errs...)
+}
+
+// LoginCodeArtifact requests a CodeArtifact authorization token and the
+// endpoints of the repositories, then writes the configuration files of their
+// package managers to the CodeArtifact directory.
+func (c *Config) LoginCodeArtifact(ctx context.Context, creds *aws.Credentials) error {
+	if c.codeArtifact == nil {
+		return nil
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(credentials.StaticCredentialsProvider{Value: *creds}), config.WithRegion(c.AWS.Region))
+	if err != nil {
+		return err
+	}
+
+	client := codeartifact.NewFromConfig(cfg)
+
+	var domainOwner *string
+	if c.codeArtifact.DomainOwner != "" {
+		domainOwner = aws.String(c.codeArtifact.DomainOwner)
+	}
+
+	// a duration of 0 makes the token expire with the session
+	output, err := client.GetAuthorizationToken(ctx, &codeartifact.GetAuthorizationTokenInput{
+		Domain:          aws.String(c.codeArtifact.Domain),
+		DomainOwner:     domainOwner,
+		DurationSeconds: aws.Int64(c.codeArtifact.DurationSeconds),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to get CodeArtifact authorization token for %s: %w", c.codeArtifact.Domain, err)
+	}
+
+	token := aws.ToString(output.AuthorizationToken)
+
+	c.redact(token)
+
+	repos := slices.Clone(c.codeArtifact.Repositories)
+
+	var errs []error
+
+	for i, repo := range repos {
+		endpoint, err := client.GetRepositoryEndpoint(ctx, &codeartifact.GetRepositoryEndpointInput{
+			Domain:      aws.String(c.codeArtifact.Domain),
+			DomainOwner: domainOwner,
+			Repository:  aws.String(repo.Repository),
+			Format:      types.PackageFormat(repo.Format),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to get CodeArtifact endpoint of %s: %w", repo.Repository, err))
+
+			continue
+		}
+
+		repos[i].endpoint = aws.ToString(endpoint.RepositoryEndpoint)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	files := map[string]string{}
+
+	if content := npmrc(repos, token); content != "" {
+		files[".npmrc"] = content
+	}
+
+	if content := pipConf(repos, token); content != "" {
+		files["pip.conf"] = content
+	}
+
+	if slices.ContainsFunc(repos, isMavenRepository) {
+		settings, err := mavenSettings(c.codeArtifact.Domain, repos, token)
+		if err != nil {
+			return err
+		}
+
+		files["settings.xml"] = settings
+		files["gradle.properties"] = gradleProperties(repos, token)
+	}
+
+	for _, name := range sortedKeys(files) {
+		path := filepath.Join(c.CodeArtifactDir, name)
+
+		err = writeSecretFile(path, files[name])
+		if err != nil {
+			return fmt.Errorf("unable to write %s: %w", name, err)
+		}
+
+		c.trackFile(path)
+	}
+
+	c.Logger.Infof("wrote %s to %s for CodeArtifact domain %s", strings.Join(sortedKeys(files), ", "), c.CodeArtifactDir, c.codeArtifact.Domain)
+
+	if output.Expiration != nil {
+		c.Logger.Infof("CodeArtifact token expires at %s", output.Expiration.UTC().Format("2006-01-02T15:04:05Z"))
+	}
+
+	return nil
+}
+
+// isMavenRepository reports whether the repository serves Maven packages.
+func isMavenRepository(repo CodeArtifactRepository) bool {
+	return repo.Format == string(types.PackageFormatMaven)
+}
+
+// npmrc returns the npm configuration for the npm repositories, or nothing
+// when there are none.
+func npmrc(repos []CodeArtifactRepository, token string) string {
+	var b strings.Builder
+
+	for _, repo := range repos {
+		if repo.Format != string(types.PackageFormatNpm) {
+			continue
+		}
+
+		if repo.Scope == "" {
+			fmt.Fprintf(&b, "registry=%s\n", repo.endpoint)
+		} else {
+			fmt.Fprintf(&b, "%s:registry=%s\n", repo.Scope, repo.endpoint)
+		}
+
+		// npm matches the token to the registry by its URL without the scheme
+		fmt.Fprintf(&b, "//%s:_authToken=%s\n", strings.TrimPrefix(repo.endpoint, "https://"), token)
+	}
+
+	return b.String()
+}
+
+// pipConf returns the pip configuration using the first PyPI repository as
+// the index and the others as extra indexes, or nothing when there are none.
+func pipConf(repos []CodeArtifactRepository, token string) string {
+	var indexes []string
+
+	for _, repo := range repos {
+		if repo.Format != string(types.PackageFormatPypi) {
+			continue
+		}
+
+		index, err := url.Parse(strings.TrimSuffix(repo.endpoint, "/") + "/simple/")
+		if err != nil {
+			continue
+		}
+
+		index.User = url.UserPassword(codeArtifactUsername, token)
+
+		indexes = append(indexes, index.String())
+	}
+
+	if len(indexes) == 0 {
+		return ""
+	}
+
+	conf := "[global]\nindex-url = " + indexes[0] + "\n"
+	if len(indexes) > 1 {
+		conf += "extra-index-url =\n    " + strings.Join(indexes[1:], "\n    ") + "\n"
+	}
+
+	return conf
+}
+
+type (
+	// mavenSettingsXML represents the parts of a Maven settings.xml written for CodeArtifact.
+	mavenSettingsXML struct {
+		XMLName        xml.Name          `xml:"settings"`
+		Servers        []mavenServerXML  `xml:"servers>server"`
+		Profiles       []mavenProfileXML `xml:"profiles>profile"`
+		ActiveProfiles []string          `xml:"activeProfiles>activeProfile"`
+	}
+
+	// mavenServerXML represents the credentials of a Maven repository.
+	mavenServerXML struct {
+		ID       string `xml:"id"`
+		Username string `xml:"username"`
+		Password string `xml:"password"`
+	}
+
+	// mavenProfileXML represents a Maven profile declaring repositories.
+	mavenProfileXML struct {
+		ID           string               `xml:"id"`
+		Repositories []mavenRepositoryXML `xml:"repositories>repository"`
+	}
+
+	// mavenRepositoryXML represents a Maven repository.
+	mavenRepositoryXML struct {
+		ID  string `xml:"id"`
+		URL string `xml:"url"`
+	}
+)
+
+// mavenSettings returns a Maven settings.xml declaring the Maven repositories,
+// with ids of the form domain-repository, in an active profile.
+func mavenSettings(domain string, repos []CodeArtifactRepository, token string) (string, error) {
+	settings := mavenSettingsXML{
+		Profiles:       []mavenProfileXML{{ID: codeArtifactMavenProfile}},
+		ActiveProfiles: []string{codeArtifactMavenProfile},
+	}
+
+	for _, repo := range repos {
+		if !isMavenRepository(repo) {
+			continue
+		}
+
+		id := domain + "-" + repo.Repository
+
+		settings.Servers = append(settings.Servers, mavenServerXML{ID: id, Username: codeArtifactUsername, Password: token})
+		settings.Profiles[0].Repositories = append(settings.Profiles[0].Repositories, mavenRepositoryXML{ID: id, URL: repo.endpoint})
+	}
+
+	data, err := xml.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return xml.Header + string(data) + "\n", nil
+}
+
+// gradleProperties returns Gradle properties with the token and, for each
+// Maven repository, its URL and credentials under codeartifact.<repository>.
+func gradleProperties(repos []CodeArtifactRepository, token string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "codeartifactToken=%s\n", token)
+
+	for _, repo := range repos {
+		if !isMavenRepository(repo) {
+			continue
+		}
+
+		prefix := "codeartifact." + repo.Repository
+
+		fmt.Fprintf(&b, "%s.url=%s\n", prefix, repo.endpoint)
+		fmt.Fprintf(&b, "%s.username=%s\n", prefix, codeArtifactUsername)
+		fmt.Fprintf(&b, "%s.password=%s\n", prefix, token)
+	}
+
+	return b.String()
+}
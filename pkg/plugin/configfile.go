@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"go.yaml.in/yaml/v3"
+)
+
+// parameterEnvPrefix represents the prefix of the environment variables Vela
+// sets for the parameters of a step.
+const parameterEnvPrefix = "PARAMETER_"
+
+// structuredParameters represents the string parameters holding a JSON or
+// YAML document, which a config file may write as a nested value.
+var structuredParameters = []string{
+	FlagAWSInlineSessionPolicy,
+	FlagAWSRoleRules,
+	FlagCodeArtifact,
+	FlagEKSClusters,
+	FlagSecrets,
+	FlagVerifyProbes,
+}
+
+// Parameter represents a plugin parameter that may be set in the step or a config file.
+type Parameter struct {
+	// Name is the name of the parameter in the step, e.g. role_duration_seconds.
+	Name string
+	// Flag is the CLI flag the parameter sets.
+	Flag cli.DocGenerationFlag
+}
+
+// Parameters returns the parameters of the plugin, derived from the flags
+// read from PARAMETER_ environment variables, sorted by name. Flags only read
+// from Vela or admin secrets are not parameters.
+func Parameters() []Parameter {
+	var params []Parameter
+
+	for _, flag := range Flags {
+		f, ok := flag.(cli.DocGenerationFlag)
+		if !ok || f.Names()[0] == FlagConfigFile {
+			continue
+		}
+
+		for _, env := range f.GetEnvVars() {
+			if name, ok := strings.CutPrefix(env, parameterEnvPrefix); ok {
+				params = append(params, Parameter{Name: strings.ToLower(name), Flag: f})
+
+				break
+			}
+		}
+	}
+
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].Name < params[j].Name
+	})
+
+	return params
+}
+
+// ParseConfigFile parses a YAML or JSON config file mapping parameter names to
+// values, rejecting keys that are not parameters.
+func ParseConfigFile(data []byte) (map[string]any, error) {
+	values := map[string]any{}
+
+	err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&values)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to parse config file: %w", err)
+	}
+
+	known := map[string]bool{}
+	for _, param := range Parameters() {
+		known[param.Name] = true
+	}
+
+	var unknown []string
+
+	for key := range values {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+
+		return nil, fmt.Errorf("unknown parameters in config file: %s", strings.Join(unknown, ", "))
+	}
+
+	return values, nil
+}
+
+// ApplyConfigFile reads the config file at path and sets every parameter it
+// contains that was not already set in the step, so parameters override the
+// file.
+func ApplyConfigFile(ctx *cli.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return newError(ErrorKindConfig, fmt.Errorf("unable to read config file: %w", err))
+	}
+
+	values, err := ParseConfigFile(data)
+	if err != nil {
+		return newError(ErrorKindConfig, err)
+	}
+
+	var errs []error
+
+	for _, param := range Parameters() {
+		v, ok := values[param.Name]
+		if !ok {
+			continue
+		}
+
+		name := param.Flag.Names()[0]
+		if ctx.IsSet(name) {
+			continue
+		}
+
+		value, err := parameterValue(param, v)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		err = ctx.Set(name, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for parameter %s in config file: %w", param.Name, err))
+		}
+	}
+
+	return newError(ErrorKindConfig, errors.Join(errs...))
+}
+
+// parameterValue converts a config file value to the string form of the parameter.
+func parameterValue(param Parameter, v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []any, map[string]any:
+		if slices.Contains(structuredParameters, param.Flag.Names()[0]) {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return "", fmt.Errorf("invalid value for parameter %s in config file: %w", param.Name, err)
+			}
+
+			return string(data), nil
+		}
+
+		list, ok := v.([]any)
+		if sliceFlag, isSlice := param.Flag.(cli.DocGenerationSliceFlag); !ok || !isSlice || !sliceFlag.IsSliceFlag() {
+			return "", fmt.Errorf("parameter %s in config file must be a single value", param.Name)
+		}
+
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			switch item.(type) {
+			case []any, map[string]any:
+				return "", fmt.Errorf("parameter %s in config file must be a list of single values", param.Name)
+			}
+
+			values = append(values, fmt.Sprint(item))
+		}
+
+		return strings.Join(values, ","), nil
+	}
+
+	return fmt.Sprint(v), nil
+}
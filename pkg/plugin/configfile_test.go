@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			data: "role: arn:aws:iam::123456123456:role/test\nverify: true\n",
+			want: map[string]any{"role": "arn:aws:iam::123456123456:role/test", "verify": true},
+		},
+		{
+			name: "json",
+			data: `{"role": "arn:aws:iam::123456123456:role/test", "role_duration_seconds": 900}`,
+			want: map[string]any{"role": "arn:aws:iam::123456123456:role/test", "role_duration_seconds": 900},
+		},
+		{
+			name: "empty",
+			data: "",
+			want: map[string]any{},
+		},
+		{
+			name:    "unknown keys",
+			data:    "role: arn:aws:iam::123456123456:role/test\nrole_arn: test\nregoin: us-east-1\n",
+			wantErr: "unknown parameters in config file: regoin, role_arn",
+		},
+		{
+			name:    "vela variables are not parameters",
+			data:    "workspace: /vela/src\n",
+			wantErr: "unknown parameters in config file: workspace",
+		},
+		{
+			name:    "config file cannot include itself",
+			data:    "config_file: other.yml\n",
+			wantErr: "unknown parameters in config file: config_file",
+		},
+		{
+			name:    "admin secrets are not parameters",
+			data:    "allowlist: {}\naudit_file: /dev/null\naudit_webhook: https://example.com\n",
+			wantErr: "unknown parameters in config file: allowlist, audit_file, audit_webhook",
+		},
+		{
+			name:    "not a mapping",
+			data:    "- role\n",
+			wantErr: "unable to parse config file",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseConfigFile([]byte(test.data))
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aws.yml")
+
+	err := os.WriteFile(path, []byte(`
+role: arn:aws:iam::123456123456:role/test
+region: us-west-2
+role_duration_seconds: 900
+verify: true
+retry_jitter: 0.25
+assume_role_timeout: 30s
+additional_regions: [us-future-1, us-future-2]
+duration_ladder: [3600, 900]
+role_rules:
+  - name: main
+    branch: main
+    role: arn:aws:iam::123456123456:role/deploy
+`), 0600)
+	if err != nil {
+		t.Fatalf("unable to write config file: %v", err)
+	}
+
+	// parameters set in the step override the config file
+	t.Setenv("PARAMETER_REGION", "eu-west-1")
+
+	var got *Config
+
+	app := &cli.App{
+		Flags: Flags,
+		Action: func(ctx *cli.Context) error {
+			err := ApplyConfigFile(ctx, path)
+			if err != nil {
+				return err
+			}
+
+			got = FromCLIContext(ctx, nil)
+
+			return nil
+		},
+	}
+
+	err = app.Run([]string{"vela-aws-credentials"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "arn:aws:iam::123456123456:role/test", got.AWS.Role)
+	assert.Equal(t, "eu-west-1", got.AWS.Region)
+	assert.Equal(t, 900, got.AWS.RoleDurationSeconds)
+	assert.True(t, got.Verify)
+	assert.Equal(t, 0.25, got.Retry.Jitter)
+	assert.Equal(t, "30s", got.Timeout.AssumeRole.String())
+	assert.Equal(t, []string{"us-future-1", "us-future-2"}, got.AWS.AdditionalRegions)
+	assert.Equal(t, []int{3600, 900}, got.AWS.DurationLadder)
+	assert.JSONEq(t, `[{"name":"main","branch":"main","role":"arn:aws:iam::123456123456:role/deploy"}]`, got.AWS.RoleRules)
+
+	// the defaults of parameters missing from the config file are kept
+	assert.Equal(t, ScriptFormatShell, got.ScriptFormat)
+}
+
+func TestApplyConfigFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "unknown key",
+			data:    "rol: arn:aws:iam::123456123456:role/test\n",
+			wantErr: "configuration error: unknown parameters in config file: rol",
+		},
+		{
+			name:    "list for a single value",
+			data:    "role: [a, b]\n",
+			wantErr: "parameter role in config file must be a single value",
+		},
+		{
+			name:    "invalid integer",
+			data:    "role_duration_seconds: an hour\n",
+			wantErr: "invalid value for parameter role_duration_seconds in config file",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "aws.yml")
+
+			err := os.WriteFile(path, []byte(test.data), 0600)
+			if err != nil {
+				t.Fatalf("unable to write config file: %v", err)
+			}
+
+			app := &cli.App{
+				Flags: Flags,
+				Action: func(ctx *cli.Context) error {
+					return ApplyConfigFile(ctx, path)
+				},
+			}
+
+			err = app.Run([]string{"vela-aws-credentials"})
+			assert.ErrorContains(t, err, test.wantErr)
+			assert.Equal(t, 2, ExitCode(err))
+		})
+	}
+}
@@ -1,40 +1,151 @@
 package plugin
 
 const (
+	// FlagAction represents the name of the flag for setting whether to issue credentials or clean up after earlier steps for the plugin.
+	FlagAction = "action"
+	// FlagAllowlist represents the name of the flag for setting the role allowlist provided by an admin secret for the plugin.
+	FlagAllowlist = "allowlist"
+	// FlagAuditFile represents the name of the flag for setting the path of the file to append audit events to provided by an admin secret for the plugin.
+	FlagAuditFile = "audit_file"
+	// FlagAuditWebhook represents the name of the flag for setting the URL to send audit events to provided by an admin secret for the plugin.
+	FlagAuditWebhook = "audit_webhook"
 	// FlagAudience represents the name of the flag for setting the OIDC provider audience for the plugin.
 	FlagAudience = "audience"
+	// FlagConfigFile represents the name of the flag for setting the path of a YAML or JSON file of parameters for the plugin.
+	FlagConfigFile = "config_file"
+	// FlagDryRun represents the name of the flag for setting whether to explain the role assumption without calling AWS for the plugin.
+	FlagDryRun = "dry_run"
+	// FlagDryRunToken represents the name of the flag for setting whether to request and decode the ID token during a dry run for the plugin.
+	FlagDryRunToken = "dry_run_token"
+	// FlagIssuer represents the name of the flag for setting the expected ID token issuer for the plugin.
+	FlagIssuer = "issuer"
+	// FlagJWKSURL represents the name of the flag for setting the URL of the issuer's JWKS for the plugin.
+	FlagJWKSURL = "jwks_url"
 	// FlagLogFormat represents the name of the flag for setting the log format for the plugin.
 	FlagLogFormat = "log.format"
 	// FlagLogLevel represents the name of the flag for setting the log level for the plugin.
 	FlagLogLevel = "log.level"
+	// FlagManifestPath represents the name of the flag for setting the path of the manifest of written files for the plugin.
+	FlagManifestPath = "manifest_path"
+	// FlagOutputs represents the name of the flag for setting whether to write the session details to the Vela outputs file for the plugin.
+	FlagOutputs = "outputs"
+	// FlagOutputsMasked represents the name of the flag for setting whether to write the credentials to the Vela masked outputs file for the plugin.
+	FlagOutputsMasked = "masked_outputs"
+	// FlagSecrets represents the name of the flag for setting the Secrets Manager secrets and SSM parameters to fetch for the plugin.
+	FlagSecrets = "secrets"
+	// FlagSecretsEnvFile represents the name of the flag for setting the path of the env file to write fetched secrets to for the plugin.
+	FlagSecretsEnvFile = "secrets_env_file"
+	// FlagECRRegistries represents the name of the flag for setting the ECR registries to log in to for the plugin.
+	FlagECRRegistries = "ecr_registries"
+	// FlagDockerConfig represents the name of the flag for setting the path of the Docker config file to write ECR logins to for the plugin.
+	FlagDockerConfig = "docker_config"
+	// FlagCodeArtifact represents the name of the flag for setting the CodeArtifact domain and repositories to log in to for the plugin.
+	FlagCodeArtifact = "codeartifact"
+	// FlagCodeArtifactDir represents the name of the flag for setting the directory to write the package manager configuration files to for the plugin.
+	FlagCodeArtifactDir = "codeartifact_dir"
+	// FlagEKSClusters represents the name of the flag for setting the EKS clusters to write to the kubeconfig for the plugin.
+	FlagEKSClusters = "eks_clusters"
+	// FlagKubeconfig represents the name of the flag for setting the path of the kubeconfig for the plugin.
+	FlagKubeconfig = "kubeconfig"
+	// FlagKubeconfigAuth represents the name of the flag for setting how the kubeconfig users authenticate for the plugin.
+	FlagKubeconfigAuth = "kubeconfig_auth"
+	// FlagKubeconfigExecCommand represents the name of the flag for setting the command of the kubeconfig exec plugin for the plugin.
+	FlagKubeconfigExecCommand = "kubeconfig_exec_command"
 	// FlagScriptFormat represents the name of the flag for setting the format of the AWS credentials script for the plugin.
 	FlagScriptFormat = "script_format"
 	// FlagScriptPath represents the name of the flag for setting the path to write the AWS credentials script for the plugin.
 	FlagScriptPath = "script_path"
 	// FlagScriptWrite represents the name of the flag for setting whether to write the AWS credentials script for the plugin.
 	FlagScriptWrite = "script_write"
+	// FlagTimeout represents the name of the flag for setting the overall timeout for the plugin.
+	FlagTimeout = "timeout"
+	// FlagTimeoutToken represents the name of the flag for setting the timeout for requesting the ID token for the plugin.
+	FlagTimeoutToken = "token_timeout"
+	// FlagTimeoutAssumeRole represents the name of the flag for setting the timeout for assuming the AWS IAM role for the plugin.
+	FlagTimeoutAssumeRole = "assume_role_timeout"
+	// FlagTimeoutVerify represents the name of the flag for setting the timeout for verifying the AWS credentials for the plugin.
+	FlagTimeoutVerify = "verify_timeout"
+	// FlagTimeoutSecrets represents the name of the flag for setting the timeout for fetching the secrets for the plugin.
+	FlagTimeoutSecrets = "secrets_timeout"
+	// FlagTimeoutECR represents the name of the flag for setting the timeout for logging in to the ECR registries for the plugin.
+	FlagTimeoutECR = "ecr_timeout"
+	// FlagTimeoutCodeArtifact represents the name of the flag for setting the timeout for logging in to CodeArtifact for the plugin.
+	FlagTimeoutCodeArtifact = "codeartifact_timeout"
+	// FlagTimeoutKubeconfig represents the name of the flag for setting the timeout for writing the kubeconfig for the plugin.
+	FlagTimeoutKubeconfig = "kubeconfig_timeout"
+	// FlagTimeoutRevoke represents the name of the flag for setting the timeout for revoking the sessions for the plugin.
+	FlagTimeoutRevoke = "revoke_timeout"
 	// FlagVerify represents the name of the flag for setting whether to validate the AWS credentials for the plugin.
 	FlagVerify = "verify"
+	// FlagVerifyAccountID represents the name of the flag for setting the account ID the AWS credentials are expected to belong to for the plugin.
+	FlagVerifyAccountID = "verify_account_id"
+	// FlagVerifyAssumedRoleARN represents the name of the flag for setting the assumed role ARN the AWS credentials are expected to belong to for the plugin.
+	FlagVerifyAssumedRoleARN = "verify_assumed_role_arn"
+	// FlagVerifyProbes represents the name of the flag for setting the checks to run with the AWS credentials for the plugin.
+	FlagVerifyProbes = "verify_probes"
+	// FlagVerifyToken represents the name of the flag for setting whether to verify the ID token signature for the plugin.
+	FlagVerifyToken = "verify_token"
 
 	// AWS Configuration Flags.
 
+	// FlagAWSAdditionalRegions represents the name of the flag for setting regions to accept in addition to the known AWS regions for the plugin.
+	FlagAWSAdditionalRegions = "aws.additional_regions"
+	// FlagAWSConfigFile represents the name of the flag for setting the path of the AWS shared config file to read profiles from for the plugin.
+	FlagAWSConfigFile = "aws.config_file"
+	// FlagAWSDurationFallback represents the name of the flag for setting whether to retry with shorter durations when the role's maximum session duration is exceeded for the plugin.
+	FlagAWSDurationFallback = "aws.duration_fallback"
+	// FlagAWSDurationLadder represents the name of the flag for setting the durations to fall back to, in order, for the plugin.
+	FlagAWSDurationLadder = "aws.duration_ladder"
 	// FlagAWSInlineSessionPolicy represents the name of the flag for setting the AWS inline session policy for the plugin.
 	FlagAWSInlineSessionPolicy = "aws.inline_session_policy"
+	// FlagAWSInlineSessionPolicyFile represents the name of the flag for setting the path to a file containing the AWS inline session policy for the plugin.
+	FlagAWSInlineSessionPolicyFile = "aws.inline_session_policy_file"
 	// FlagAWSManagedSessionPolicies represents the name of the flag for setting the AWS managed session policies for the plugin.
 	FlagAWSManagedSessionPolicies = "aws.managed_session_policies"
+	// FlagAWSProfile represents the name of the flag for setting the profile of the AWS shared config file to assume for the plugin.
+	FlagAWSProfile = "aws.profile"
 	// FlagAWSRegion represents the name of the flag for setting the AWS region for the plugin.
 	FlagAWSRegion = "aws.region"
 	// FlagAWSRole represents the name of the flag for setting the AWS IAM role to assume for the plugin.
 	FlagAWSRole = "aws.role"
+	// FlagAWSRevokeRole represents the name of the flag for setting the AWS IAM role used to revoke sessions during cleanup for the plugin.
+	FlagAWSRevokeRole = "aws.revoke_role"
 	// FlagAWSRoleDurationSeconds represents the name of the flag for setting the duration in seconds for assuming the AWS IAM role for the plugin.
 	FlagAWSRoleDurationSeconds = "aws.role_duration_seconds"
+	// FlagAWSRoleRules represents the name of the flag for setting the ordered rules selecting the AWS IAM role to assume for the plugin.
+	FlagAWSRoleRules = "aws.role_rules"
 	// FlagAWSRoleSessionName represents the name of the flag for setting the session name when assuming the AWS IAM role for the plugin.
 	FlagAWSRoleSessionName = "aws.role_session_name"
 
+	// Retry Configuration Flags.
+
+	// FlagRetryAttempts represents the name of the flag for setting the number of attempts for each API call for the plugin.
+	FlagRetryAttempts = "retry.attempts"
+	// FlagRetryBaseDelay represents the name of the flag for setting the delay before the first retry for the plugin.
+	FlagRetryBaseDelay = "retry.base_delay"
+	// FlagRetryMaxDelay represents the name of the flag for setting the maximum delay between retries for the plugin.
+	FlagRetryMaxDelay = "retry.max_delay"
+	// FlagRetryJitter represents the name of the flag for setting the fraction of each delay to randomize for the plugin.
+	FlagRetryJitter = "retry.jitter"
+
 	// Vela Configuration Flags.
 
+	// FlagVelaBuildAuthor represents the name of the flag for capturing the build author from Vela for the plugin.
+	FlagVelaBuildAuthor = "vela.build_author"
+	// FlagVelaBuildBranch represents the name of the flag for capturing the build branch from Vela for the plugin.
+	FlagVelaBuildBranch = "vela.build_branch"
+	// FlagVelaBuildCommit represents the name of the flag for capturing the build commit SHA from Vela for the plugin.
+	FlagVelaBuildCommit = "vela.build_commit"
+	// FlagVelaBuildEvent represents the name of the flag for capturing the build event from Vela for the plugin.
+	FlagVelaBuildEvent = "vela.build_event"
+	// FlagVelaBuildLink represents the name of the flag for capturing the build link from Vela for the plugin.
+	FlagVelaBuildLink = "vela.build_link"
 	// FlagVelaBuildNumber represents the name of the flag for capturing the build number from Vela for the plugin.
 	FlagVelaBuildNumber = "vela.build_number"
+	// FlagVelaBuildTag represents the name of the flag for capturing the build tag from Vela for the plugin.
+	FlagVelaBuildTag = "vela.build_tag"
+	// FlagVelaDeployment represents the name of the flag for capturing the deployment target from Vela for the plugin.
+	FlagVelaDeployment = "vela.deployment"
 	// FlagVelaIDTokenRequestToken represents the name of the flag for capturing the OIDC request token from Vela for the plugin.
 	//
 	//nolint:gosec // ignore false positive for hardcoded credential
@@ -43,10 +154,26 @@ const (
 	//
 	//nolint:gosec // ignore false positive for hardcoded credential
 	FlagVelaIDTokenRequestURL = "vela.id_token_request_url"
+	// FlagVelaMaskedOutputs represents the name of the flag for capturing the path of the masked outputs file from Vela for the plugin.
+	FlagVelaMaskedOutputs = "vela.masked_outputs"
 	// FlagVelaOrgName represents the name of the flag for capturing the organization name from Vela for the plugin.
 	FlagVelaOrgName = "vela.org_name"
+	// FlagVelaOutputs represents the name of the flag for capturing the path of the outputs file from Vela for the plugin.
+	FlagVelaOutputs = "vela.outputs"
 	// FlagVelaRepoName represents the name of the flag for capturing the repository name from Vela for the plugin.
 	FlagVelaRepoName = "vela.repo_name"
+	// FlagVelaWorkspace represents the name of the flag for capturing the build workspace from Vela for the plugin.
+	FlagVelaWorkspace = "vela.workspace"
+
+	// ActionCleanup represents the value for the action flag to delete the files written by earlier steps.
+	ActionCleanup = "cleanup"
+	// ActionCredentials represents the value for the action flag to issue AWS credentials.
+	ActionCredentials = "credentials"
+
+	// KubeconfigAuthExec represents the value for the kubeconfig auth flag to authenticate by running the plugin as an exec plugin.
+	KubeconfigAuthExec = "exec"
+	// KubeconfigAuthToken represents the value for the kubeconfig auth flag to embed a presigned token.
+	KubeconfigAuthToken = "token"
 
 	// ScriptFormatCredentialFile represents the value for the script format flag to write AWS credentials as a credential file.
 	//
@@ -8,24 +8,89 @@ import (
 // FromCLIContext creates and returns a plugin from the urfave/cli context.
 func FromCLIContext(ctx *cli.Context, logger *logrus.Entry) *Config {
 	return &Config{
-		Logger:       logger,
-		Audience:     ctx.String(FlagAudience),
-		ScriptPath:   ctx.String(FlagScriptPath),
-		ScriptFormat: ctx.String(FlagScriptFormat),
-		ScriptWrite:  ctx.Bool(FlagScriptWrite),
-		Verify:       ctx.Bool(FlagVerify),
+		Logger:               logger,
+		Action:               ctx.String(FlagAction),
+		Audience:             ctx.String(FlagAudience),
+		DryRun:               ctx.Bool(FlagDryRun),
+		DryRunToken:          ctx.Bool(FlagDryRunToken),
+		Issuer:               ctx.String(FlagIssuer),
+		JWKSURL:              ctx.String(FlagJWKSURL),
+		ScriptPath:           ctx.String(FlagScriptPath),
+		ScriptFormat:         ctx.String(FlagScriptFormat),
+		ScriptWrite:          ctx.Bool(FlagScriptWrite),
+		Verify:               ctx.Bool(FlagVerify),
+		VerifyToken:          ctx.Bool(FlagVerifyToken),
+		VerifyAccountID:      ctx.String(FlagVerifyAccountID),
+		VerifyAssumedRoleARN: ctx.String(FlagVerifyAssumedRoleARN),
+		VerifyProbes:         ctx.String(FlagVerifyProbes),
+		Allowlist:            ctx.String(FlagAllowlist),
+		AllowlistFile:        AllowlistPath,
+		AuditFile:            ctx.String(FlagAuditFile),
+		AuditWebhook:         ctx.String(FlagAuditWebhook),
+		ManifestPath:         ctx.String(FlagManifestPath),
+		Secrets:              ctx.String(FlagSecrets),
+		SecretsEnvFile:       ctx.String(FlagSecretsEnvFile),
+		ECRRegistries:        ctx.StringSlice(FlagECRRegistries),
+		DockerConfig:         ctx.String(FlagDockerConfig),
+		CodeArtifact:         ctx.String(FlagCodeArtifact),
+		CodeArtifactDir:      ctx.String(FlagCodeArtifactDir),
+		EKSClusters:          ctx.String(FlagEKSClusters),
+		Kubeconfig:           ctx.String(FlagKubeconfig),
+		KubeconfigAuth:       ctx.String(FlagKubeconfigAuth),
+		KubeExecCommand:      ctx.String(FlagKubeconfigExecCommand),
+		Outputs: &Outputs{
+			Write:       ctx.Bool(FlagOutputs),
+			WriteMasked: ctx.Bool(FlagOutputsMasked),
+			Path:        ctx.String(FlagVelaOutputs),
+			MaskedPath:  ctx.String(FlagVelaMaskedOutputs),
+		},
 		AWS: &AWS{
-			Region:                 ctx.String(FlagAWSRegion),
-			Role:                   ctx.String(FlagAWSRole),
-			RoleDurationSeconds:    ctx.Int(FlagAWSRoleDurationSeconds),
-			RoleSessionName:        ctx.String(FlagAWSRoleSessionName),
-			InlineSessionPolicy:    ctx.String(FlagAWSInlineSessionPolicy),
-			ManagedSessionPolicies: ctx.StringSlice(FlagAWSManagedSessionPolicies),
+			Region:                  ctx.String(FlagAWSRegion),
+			AdditionalRegions:       ctx.StringSlice(FlagAWSAdditionalRegions),
+			Role:                    ctx.String(FlagAWSRole),
+			RoleDurationSeconds:     ctx.Int(FlagAWSRoleDurationSeconds),
+			DurationFallback:        ctx.Bool(FlagAWSDurationFallback),
+			DurationLadder:          ctx.IntSlice(FlagAWSDurationLadder),
+			RoleSessionName:         ctx.String(FlagAWSRoleSessionName),
+			InlineSessionPolicy:     ctx.String(FlagAWSInlineSessionPolicy),
+			InlineSessionPolicyFile: ctx.String(FlagAWSInlineSessionPolicyFile),
+			ManagedSessionPolicies:  ctx.StringSlice(FlagAWSManagedSessionPolicies),
+			RoleRules:               ctx.String(FlagAWSRoleRules),
+			RevokeRole:              ctx.String(FlagAWSRevokeRole),
+			Profile:                 ctx.String(FlagAWSProfile),
+			ConfigFile:              ctx.String(FlagAWSConfigFile),
+		},
+		Retry: &Retry{
+			Attempts:  ctx.Int(FlagRetryAttempts),
+			BaseDelay: ctx.Duration(FlagRetryBaseDelay),
+			MaxDelay:  ctx.Duration(FlagRetryMaxDelay),
+			Jitter:    ctx.Float64(FlagRetryJitter),
+		},
+		Timeout: &Timeout{
+			Overall:      ctx.Duration(FlagTimeout),
+			Token:        ctx.Duration(FlagTimeoutToken),
+			AssumeRole:   ctx.Duration(FlagTimeoutAssumeRole),
+			Verify:       ctx.Duration(FlagTimeoutVerify),
+			Secrets:      ctx.Duration(FlagTimeoutSecrets),
+			ECR:          ctx.Duration(FlagTimeoutECR),
+			CodeArtifact: ctx.Duration(FlagTimeoutCodeArtifact),
+			Kubeconfig:   ctx.Duration(FlagTimeoutKubeconfig),
+			Revoke:       ctx.Duration(FlagTimeoutRevoke),
+		},
+		Build: &BuildContext{
+			Org:         ctx.String(FlagVelaOrgName),
+			Repo:        ctx.String(FlagVelaRepoName),
+			BuildNumber: ctx.Int(FlagVelaBuildNumber),
+			Branch:      ctx.String(FlagVelaBuildBranch),
+			Event:       ctx.String(FlagVelaBuildEvent),
+			Commit:      ctx.String(FlagVelaBuildCommit),
+			Author:      ctx.String(FlagVelaBuildAuthor),
+			Tag:         ctx.String(FlagVelaBuildTag),
+			Target:      ctx.String(FlagVelaDeployment),
+			Workspace:   ctx.String(FlagVelaWorkspace),
+			Link:        ctx.String(FlagVelaBuildLink),
 		},
 		Vela: &Vela{
-			BuildNumber:     ctx.Int(FlagVelaBuildNumber),
-			RepoName:        ctx.String(FlagVelaRepoName),
-			OrgName:         ctx.String(FlagVelaOrgName),
 			RequestToken:    ctx.String(FlagVelaIDTokenRequestToken),
 			RequestTokenURL: ctx.String(FlagVelaIDTokenRequestURL),
 		},
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
@@ -12,24 +13,79 @@ import (
 func TestPlugin_FromCLIContext(t *testing.T) {
 	// setup types
 	flags := flag.NewFlagSet("test", 0)
+	flags.String(FlagAction, ActionCredentials, "doc")
+	flags.String(FlagAuditFile, "/vela/audit.json", "doc")
+	flags.String(FlagAuditWebhook, "https://audit.example.com", "doc")
 	flags.String(FlagAudience, "sts.amazonaws.com", "doc")
+	flags.String(FlagCodeArtifact, "{}", "doc")
+	flags.String(FlagCodeArtifactDir, "/vela/secrets/aws/codeartifact", "doc")
+	flags.String(FlagDockerConfig, "/vela/secrets/aws/docker/config.json", "doc")
+	flags.Bool(FlagDryRun, true, "doc")
+	flags.Bool(FlagDryRunToken, true, "doc")
+	flags.String(FlagECRRegistries, "123456123456", "doc")
+	flags.String(FlagEKSClusters, "[]", "doc")
+	flags.String(FlagIssuer, "https://vela.example.com/_services/token", "doc")
+	flags.String(FlagJWKSURL, "https://vela.example.com/_services/token/.well-known/jwks", "doc")
+	flags.String(FlagKubeconfig, "/vela/secrets/aws/kubeconfig", "doc")
+	flags.String(FlagKubeconfigAuth, KubeconfigAuthToken, "doc")
+	flags.String(FlagKubeconfigExecCommand, "vela-aws-credentials", "doc")
 	flags.String(FlagLogFormat, "json", "doc")
 	flags.String(FlagLogLevel, "info", "doc")
+	flags.String(FlagManifestPath, "/vela/secrets/aws/manifest.json", "doc")
+	flags.Bool(FlagOutputs, true, "doc")
+	flags.Bool(FlagOutputsMasked, true, "doc")
+	flags.String(FlagSecrets, "[]", "doc")
+	flags.String(FlagSecretsEnvFile, "/vela/secrets/aws/secrets.env", "doc")
 	flags.String(FlagScriptFormat, ScriptFormatShell, "doc")
 	flags.String(FlagScriptPath, "/path/to/script", "doc")
 	flags.Bool(FlagScriptWrite, true, "doc")
+	flags.Duration(FlagTimeout, 5*time.Minute, "doc")
+	flags.Duration(FlagTimeoutToken, time.Minute, "doc")
+	flags.Duration(FlagTimeoutAssumeRole, time.Minute, "doc")
+	flags.Duration(FlagTimeoutVerify, 30*time.Second, "doc")
+	flags.Duration(FlagTimeoutSecrets, time.Minute, "doc")
+	flags.Duration(FlagTimeoutECR, time.Minute, "doc")
+	flags.Duration(FlagTimeoutCodeArtifact, time.Minute, "doc")
+	flags.Duration(FlagTimeoutKubeconfig, time.Minute, "doc")
+	flags.Duration(FlagTimeoutRevoke, time.Minute, "doc")
 	flags.Bool(FlagVerify, true, "doc")
+	flags.Bool(FlagVerifyToken, true, "doc")
+	flags.String(FlagVerifyAccountID, "123456123456", "doc")
+	flags.String(FlagVerifyAssumedRoleARN, "arn:aws:sts::123456123456:assumed-role/test/vela", "doc")
+	flags.String(FlagVerifyProbes, "[]", "doc")
 
 	flags.String(FlagAWSRegion, "us-east-1", "doc")
 	flags.String(FlagAWSRole, "testRole", "doc")
 	flags.Int(FlagAWSRoleDurationSeconds, 3600, "doc")
+	flags.String(FlagAWSAdditionalRegions, "us-future-1", "doc")
+	flags.Bool(FlagAWSDurationFallback, true, "doc")
+	flags.String(FlagAWSDurationLadder, "3600,900", "doc")
 	flags.String(FlagAWSRoleSessionName, "testSession", "doc")
 	flags.String(FlagAWSInlineSessionPolicy, "{}", "doc")
+	flags.String(FlagAWSInlineSessionPolicyFile, "/path/to/policy.yml", "doc")
+	flags.String(FlagAWSProfile, "deploy", "doc")
+	flags.String(FlagAWSConfigFile, ".aws/config", "doc")
+	flags.String(FlagAWSRevokeRole, "arn:aws:iam::123456123456:role/revoke", "doc")
 	flags.String(FlagAWSManagedSessionPolicies, "[arn:aws:iam::aws:policy/ReadOnlyAccess]", "doc")
 
+	flags.Int(FlagRetryAttempts, 3, "doc")
+	flags.Duration(FlagRetryBaseDelay, time.Second, "doc")
+	flags.Duration(FlagRetryMaxDelay, 20*time.Second, "doc")
+	flags.Float64(FlagRetryJitter, 0.5, "doc")
+
 	flags.Int(FlagVelaBuildNumber, 1234, "doc")
+	flags.String(FlagVelaBuildAuthor, "octocat", "doc")
+	flags.String(FlagVelaBuildBranch, "main", "doc")
+	flags.String(FlagVelaBuildCommit, "0123456789abcdef", "doc")
+	flags.String(FlagVelaBuildEvent, "push", "doc")
+	flags.String(FlagVelaBuildLink, "https://vela.example.com/testOrg/testRepo/1234", "doc")
+	flags.String(FlagVelaBuildTag, "v1.0.0", "doc")
+	flags.String(FlagVelaDeployment, "production", "doc")
+	flags.String(FlagVelaWorkspace, "/vela/src/github.com/testOrg/testRepo", "doc")
 	flags.String(FlagVelaRepoName, "testRepo", "doc")
+	flags.String(FlagVelaMaskedOutputs, "/vela/outputs/masked.env", "doc")
 	flags.String(FlagVelaOrgName, "testOrg", "doc")
+	flags.String(FlagVelaOutputs, "/vela/outputs/base.env", "doc")
 	flags.String(FlagVelaIDTokenRequestToken, "testToken", "doc")
 	flags.String(FlagVelaIDTokenRequestURL, "http://vela.example.com", "doc")
 
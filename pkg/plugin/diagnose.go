@@ -0,0 +1,367 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+)
+
+const (
+	// maxRoleDurationSeconds represents the largest duration STS allows for an assumed role session.
+	maxRoleDurationSeconds = 43200
+
+	// actionAssumeRoleWithWebIdentity represents the IAM action used to exchange the ID token.
+	actionAssumeRoleWithWebIdentity = "sts:AssumeRoleWithWebIdentity"
+)
+
+// subjectPattern matches the documented Vela ID token subject format
+// repo:<org>/<repo>:ref:<ref>:event:<event>.
+var subjectPattern = regexp.MustCompile(`^repo:[^/:]+/[^:]+:ref:refs/(heads|tags|pull)/[^:]+:event:[a-z_]+$`)
+
+type (
+	// DiagnoseInput represents the information used to explain a failed role assumption.
+	DiagnoseInput struct {
+		Role               string
+		Audience           string
+		Claims             map[string]any
+		DurationSeconds    int
+		MaxSessionDuration int
+		TrustPolicy        *PolicyDocument
+		OIDCProviders      []string
+	}
+
+	// Finding represents the outcome of a single diagnostic check.
+	Finding struct {
+		Check   string
+		OK      bool
+		Message string
+		// Skipped is set for checks that could not be evaluated and do not
+		// count as failures.
+		Skipped bool
+	}
+)
+
+// String returns the finding formatted for display.
+func (f Finding) String() string {
+	status := "PASS"
+
+	switch {
+	case f.Skipped:
+		status = "SKIP"
+	case !f.OK:
+		status = "FAIL"
+	}
+
+	return fmt.Sprintf("[%s] %s: %s", status, f.Check, f.Message)
+}
+
+// Diagnose explains likely AssumeRoleWithWebIdentity failures for the provided input.
+func Diagnose(in *DiagnoseInput) []Finding {
+	findings := []Finding{
+		diagnoseAudience(in),
+		diagnoseSubject(in),
+		diagnoseDuration(in),
+	}
+
+	issuer, _ := in.Claims["iss"].(string)
+	provider := strings.TrimPrefix(strings.TrimPrefix(issuer, "https://"), "http://")
+
+	findings = append(findings, diagnoseProvider(in, provider))
+
+	if in.TrustPolicy != nil {
+		findings = append(findings, diagnoseTrustPolicy(in, provider)...)
+	}
+
+	return findings
+}
+
+// diagnoseAudience checks that the token audience contains the expected audience.
+func diagnoseAudience(in *DiagnoseInput) Finding {
+	f := Finding{Check: "audience"}
+
+	aud := claimValues(in.Claims, "aud")
+	if slices.Contains(aud, in.Audience) {
+		f.OK = true
+		f.Message = fmt.Sprintf("token audience %v contains %q", aud, in.Audience)
+
+		return f
+	}
+
+	f.Message = fmt.Sprintf("token audience %v does not contain %q - the audience parameter must match the client ID registered on the IAM OIDC provider", aud, in.Audience)
+
+	return f
+}
+
+// diagnoseSubject checks that the token subject follows the documented Vela format.
+func diagnoseSubject(in *DiagnoseInput) Finding {
+	f := Finding{Check: "subject"}
+
+	sub, _ := in.Claims["sub"].(string)
+	if subjectPattern.MatchString(sub) {
+		f.OK = true
+		f.Message = fmt.Sprintf("token subject %q matches the Vela format", sub)
+
+		return f
+	}
+
+	f.Message = fmt.Sprintf("token subject %q does not match the Vela format repo:<org>/<repo>:ref:<ref>:event:<event>", sub)
+
+	return f
+}
+
+// diagnoseDuration checks that the requested duration is allowed for the role.
+func diagnoseDuration(in *DiagnoseInput) Finding {
+	f := Finding{Check: "duration"}
+
+	limit := maxRoleDurationSeconds
+	if in.MaxSessionDuration > 0 {
+		limit = in.MaxSessionDuration
+	}
+
+	if in.DurationSeconds > limit {
+		f.Message = fmt.Sprintf("requested duration %ds exceeds the maximum session duration of %ds for %s", in.DurationSeconds, limit, in.Role)
+
+		return f
+	}
+
+	f.OK = true
+	f.Message = fmt.Sprintf("requested duration %ds is within the maximum session duration of %ds", in.DurationSeconds, limit)
+
+	if in.MaxSessionDuration == 0 {
+		f.Message += " allowed by STS (role maximum unknown)"
+	}
+
+	return f
+}
+
+// diagnoseProvider checks that an IAM OIDC provider exists for the token issuer.
+func diagnoseProvider(in *DiagnoseInput, provider string) Finding {
+	f := Finding{Check: "oidc provider"}
+
+	if provider == "" {
+		f.Message = "token has no iss claim"
+
+		return f
+	}
+
+	if in.OIDCProviders == nil && in.TrustPolicy == nil {
+		f.OK = true
+		f.Skipped = true
+		f.Message = fmt.Sprintf("unable to check the OIDC provider for %s without a trust policy", provider)
+
+		return f
+	}
+
+	var federated []string
+	if in.TrustPolicy != nil {
+		for _, stmt := range in.TrustPolicy.Statement {
+			federated = append(federated, stmt.Principal["Federated"]...)
+		}
+	}
+
+	suffix := ":oidc-provider/" + provider
+
+	if in.OIDCProviders != nil {
+		exists := slices.ContainsFunc(in.OIDCProviders, func(arn string) bool { return strings.HasSuffix(arn, suffix) })
+		if !exists {
+			f.Message = fmt.Sprintf("no IAM OIDC provider exists for %s in the role's account", provider)
+
+			return f
+		}
+	}
+
+	if in.TrustPolicy != nil && !slices.ContainsFunc(federated, func(arn string) bool { return strings.HasSuffix(arn, suffix) }) {
+		f.Message = fmt.Sprintf("trust policy does not trust an OIDC provider for %s (federated principals: %v)", provider, federated)
+
+		return f
+	}
+
+	f.OK = true
+	f.Message = fmt.Sprintf("OIDC provider for %s is trusted", provider)
+
+	return f
+}
+
+// diagnoseTrustPolicy evaluates the conditions of every statement in the trust
+// policy allowing sts:AssumeRoleWithWebIdentity against the token claims.
+func diagnoseTrustPolicy(in *DiagnoseInput, provider string) []Finding {
+	var (
+		findings []Finding
+		allowed  bool
+	)
+
+	for i, stmt := range in.TrustPolicy.Statement {
+		if stmt.Effect != "Allow" || !slices.ContainsFunc(stmt.Action, func(a string) bool { return matchWildcard(a, actionAssumeRoleWithWebIdentity) }) {
+			continue
+		}
+
+		name := stmt.Sid
+		if name == "" {
+			name = fmt.Sprintf("statement %d", i)
+		}
+
+		stmtOK := true
+
+		for _, operator := range sortedKeys(stmt.Condition) {
+			for _, key := range sortedKeys(stmt.Condition[operator]) {
+				f := evaluateCondition(in.Claims, provider, operator, key, stmt.Condition[operator][key])
+				f.Check = fmt.Sprintf("trust policy %s", name)
+				stmtOK = stmtOK && f.OK
+
+				findings = append(findings, f)
+			}
+		}
+
+		allowed = allowed || stmtOK
+	}
+
+	f := Finding{Check: "trust policy", OK: allowed, Message: "at least one statement allows the token"}
+	if !allowed {
+		f.Message = fmt.Sprintf("no statement allowing %s matches the token claims", actionAssumeRoleWithWebIdentity)
+	}
+
+	return append(findings, f)
+}
+
+// evaluateCondition evaluates a single trust policy condition against the token claims.
+func evaluateCondition(claims map[string]any, provider, operator, key string, values StringList) Finding {
+	f := Finding{}
+
+	idx := strings.LastIndex(key, ":")
+
+	switch {
+	case idx >= 0 && key[:idx] == provider:
+	// keys of OIDC providers are prefixed with their host, unlike global and
+	// service keys such as aws:SourceIp or aws:PrincipalTag/team
+	case idx >= 0 && strings.Contains(key[:idx], "."):
+		f.Message = fmt.Sprintf("%s %s references a different provider than the token issuer %s", operator, key, provider)
+
+		return f
+	default:
+		f.OK = true
+		f.Skipped = true
+		f.Message = fmt.Sprintf("%s %s is not evaluated", operator, key)
+
+		return f
+	}
+
+	var match func(pattern, value string) bool
+
+	switch operator {
+	case "StringEquals":
+		match = func(pattern, value string) bool { return pattern == value }
+	case "StringLike":
+		match = matchWildcard
+	default:
+		f.OK = true
+		f.Skipped = true
+		f.Message = fmt.Sprintf("%s %s is not evaluated", operator, key)
+
+		return f
+	}
+
+	got := claimValues(claims, key[idx+1:])
+
+	for _, value := range got {
+		for _, pattern := range values {
+			if match(pattern, value) {
+				f.OK = true
+				f.Message = fmt.Sprintf("%s %s: %q matches %q", operator, key, value, pattern)
+
+				return f
+			}
+		}
+	}
+
+	f.Message = fmt.Sprintf("%s %s: claim value %v does not match %v", operator, key, got, []string(values))
+
+	return f
+}
+
+// claimValues returns the values of a string or string list claim.
+func claimValues(claims map[string]any, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		var values []string
+
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+
+		return values
+	}
+
+	return nil
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// FetchRoleDetails populates the trust policy, maximum session duration and
+// OIDC providers of the role using the credentials available in the environment.
+func (in *DiagnoseInput) FetchRoleDetails(ctx context.Context, region string) error {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return err
+	}
+
+	client := iam.NewFromConfig(cfg)
+
+	name := in.Role[strings.LastIndex(in.Role, "/")+1:]
+
+	role, err := client.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(name)})
+	if err != nil {
+		return fmt.Errorf("failed to get role %s: %w", name, err)
+	}
+
+	if role.Role.MaxSessionDuration != nil {
+		in.MaxSessionDuration = int(*role.Role.MaxSessionDuration)
+	}
+
+	doc, err := url.QueryUnescape(aws.ToString(role.Role.AssumeRolePolicyDocument))
+	if err != nil {
+		return fmt.Errorf("failed to decode trust policy for role %s: %w", name, err)
+	}
+
+	in.TrustPolicy, err = ParsePolicyDocument([]byte(doc))
+	if err != nil {
+		return err
+	}
+
+	providers, err := client.ListOpenIDConnectProviders(ctx, &iam.ListOpenIDConnectProvidersInput{})
+	if err != nil {
+		return fmt.Errorf("failed to list OIDC providers: %w", err)
+	}
+
+	in.OIDCProviders = []string{}
+	for _, p := range providers.OpenIDConnectProviderList {
+		in.OIDCProviders = append(in.OIDCProviders, aws.ToString(p.Arn))
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testTrustPolicy = `{
+    "Version": "2012-10-17",
+    "Statement": {
+        "Effect": "Allow",
+        "Principal": {
+            "Federated": "arn:aws:iam::123456123456:oidc-provider/vela.example.com/_services/token"
+        },
+        "Action": "sts:AssumeRoleWithWebIdentity",
+        "Condition": {
+            "StringLike": {
+                "vela.example.com/_services/token:sub": "repo:octo-org/octo-repo:*"
+            },
+            "StringEquals": {
+                "vela.example.com/_services/token:aud": "sts.amazonaws.com"
+            }
+        }
+    }
+}`
+
+func TestPlugin_Diagnose(t *testing.T) {
+	policy, err := ParsePolicyDocument([]byte(testTrustPolicy))
+	assert.NoError(t, err)
+
+	claims := func(sub, aud string) map[string]any {
+		return map[string]any{
+			"iss": "https://vela.example.com/_services/token",
+			"aud": []any{aud},
+			"sub": sub,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		input  *DiagnoseInput
+		failed []string
+	}{
+		{
+			name: "all checks pass",
+			input: &DiagnoseInput{
+				Role:          "arn:aws:iam::123456123456:role/test",
+				Audience:      "sts.amazonaws.com",
+				Claims:        claims("repo:octo-org/octo-repo:ref:refs/heads/main:event:push", "sts.amazonaws.com"),
+				TrustPolicy:   policy,
+				OIDCProviders: []string{"arn:aws:iam::123456123456:oidc-provider/vela.example.com/_services/token"},
+			},
+		},
+		{
+			name: "audience mismatch",
+			input: &DiagnoseInput{
+				Role:        "arn:aws:iam::123456123456:role/test",
+				Audience:    "sts.amazonaws.com",
+				Claims:      claims("repo:octo-org/octo-repo:ref:refs/heads/main:event:push", "other"),
+				TrustPolicy: policy,
+			},
+			failed: []string{"audience", "trust policy statement 0", "trust policy"},
+		},
+		{
+			name: "subject from another repository",
+			input: &DiagnoseInput{
+				Role:        "arn:aws:iam::123456123456:role/test",
+				Audience:    "sts.amazonaws.com",
+				Claims:      claims("repo:octo-org/other-repo:ref:refs/heads/main:event:push", "sts.amazonaws.com"),
+				TrustPolicy: policy,
+			},
+			failed: []string{"trust policy statement 0", "trust policy"},
+		},
+		{
+			name: "malformed subject",
+			input: &DiagnoseInput{
+				Role:     "arn:aws:iam::123456123456:role/test",
+				Audience: "sts.amazonaws.com",
+				Claims:   claims("octo-org/octo-repo", "sts.amazonaws.com"),
+			},
+			failed: []string{"subject"},
+		},
+		{
+			name: "missing OIDC provider",
+			input: &DiagnoseInput{
+				Role:          "arn:aws:iam::123456123456:role/test",
+				Audience:      "sts.amazonaws.com",
+				Claims:        claims("repo:octo-org/octo-repo:ref:refs/heads/main:event:push", "sts.amazonaws.com"),
+				OIDCProviders: []string{},
+			},
+			failed: []string{"oidc provider"},
+		},
+		{
+			name: "duration beyond role maximum",
+			input: &DiagnoseInput{
+				Role:               "arn:aws:iam::123456123456:role/test",
+				Audience:           "sts.amazonaws.com",
+				Claims:             claims("repo:octo-org/octo-repo:ref:refs/tags/v1.0.0:event:tag", "sts.amazonaws.com"),
+				DurationSeconds:    7200,
+				MaxSessionDuration: 3600,
+			},
+			failed: []string{"duration"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failed []string
+
+			for _, f := range Diagnose(tt.input) {
+				if !f.OK {
+					failed = append(failed, f.Check)
+				}
+			}
+
+			assert.Equal(t, tt.failed, failed)
+		})
+	}
+}
+
+func TestPlugin_diagnoseProvider_Skipped(t *testing.T) {
+	f := diagnoseProvider(&DiagnoseInput{}, "vela.example.com/_services/token")
+
+	assert.True(t, f.Skipped, "a provider that cannot be checked should be skipped")
+	assert.Equal(t, "[SKIP] oidc provider: unable to check the OIDC provider for vela.example.com/_services/token without a trust policy", f.String())
+}
+
+func TestPlugin_evaluateCondition(t *testing.T) {
+	const provider = "vela.example.com/_services/token"
+
+	claims := map[string]any{"sub": "repo:octo-org/octo-repo:ref:refs/heads/main:event:push"}
+
+	tests := []struct {
+		name     string
+		operator string
+		key      string
+		want     string
+	}{
+		{
+			name:     "claim of the token issuer",
+			operator: "StringLike",
+			key:      provider + ":sub",
+			want:     `[PASS] : StringLike vela.example.com/_services/token:sub: "repo:octo-org/octo-repo:ref:refs/heads/main:event:push" matches "repo:octo-org/*"`,
+		},
+		{
+			name:     "claim of another provider",
+			operator: "StringLike",
+			key:      "token.actions.githubusercontent.com:sub",
+			want:     "[FAIL] : StringLike token.actions.githubusercontent.com:sub references a different provider than the token issuer " + provider,
+		},
+		{
+			name:     "global key",
+			operator: "IpAddress",
+			key:      "aws:SourceIp",
+			want:     "[SKIP] : IpAddress aws:SourceIp is not evaluated",
+		},
+		{
+			name:     "principal tag",
+			operator: "StringEquals",
+			key:      "aws:PrincipalTag/team",
+			want:     "[SKIP] : StringEquals aws:PrincipalTag/team is not evaluated",
+		},
+		{
+			name:     "unsupported operator",
+			operator: "ForAnyValue:StringLike",
+			key:      provider + ":sub",
+			want:     "[SKIP] : ForAnyValue:StringLike vela.example.com/_services/token:sub is not evaluated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := evaluateCondition(claims, provider, tt.operator, tt.key, StringList{"repo:octo-org/*", "10.0.0.0/8"})
+			assert.Equal(t, tt.want, f.String())
+		})
+	}
+}
+
+func TestPlugin_matchWildcard(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"repo:octo-org/octo-repo:*", "repo:octo-org/octo-repo:ref:refs/heads/main:event:push", true},
+		{"repo:octo-org/*:ref:refs/heads/main:*", "repo:octo-org/octo-repo:ref:refs/heads/main:event:push", true},
+		{"repo:octo-org/octo-repo:*", "repo:octo-org/other:ref:refs/heads/main:event:push", false},
+		{"v?.*", "v1.2", true},
+		{"main", "main", true},
+		{"main", "mainline", false},
+		{"*", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchWildcard(tt.pattern, tt.value))
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runDryRun explains the role assumption the plugin would perform without
+// calling AWS. When DryRunToken is enabled, the ID token is requested and its
+// claims are checked against the configuration, but it is not exchanged.
+func (c *Config) runDryRun(ctx context.Context) error {
+	c.Logger.Info("dry run enabled, no credentials will be issued")
+
+	c.Logger.WithFields(logrus.Fields{
+		"role":               c.AWS.Role,
+		"region":             c.AWS.Region,
+		"duration":           c.AWS.RoleDurationSeconds,
+		"session_name":       c.AWS.RoleSessionName,
+		"managed_policies":   c.AWS.ManagedSessionPolicies,
+		"inline_policy":      c.inlineSessionPolicy,
+		"session_policy_sha": c.sessionPolicyHash(),
+		"verify":             c.Verify,
+	}).Info("would assume role")
+
+	for i, role := range c.roleChain[:max(len(c.roleChain)-1, 0)] {
+		c.Logger.Infof("would assume role %s of profile %s with a duration of %ds to reach profile %s",
+			role.RoleARN, role.Profile, c.chainDuration(i), c.AWS.Profile)
+	}
+
+	if c.AWS.DurationFallback {
+		c.Logger.Infof("would fall back to the durations %v if %ds exceeds the role's maximum session duration",
+			c.AWS.DurationLadder, c.AWS.RoleDurationSeconds)
+	}
+
+	for _, secret := range c.secrets {
+		target := secret.File
+		if target == "" {
+			target = c.SecretsEnvFile
+		}
+
+		c.Logger.Infof("would fetch secret %s to %s", secret.Name, target)
+	}
+
+	for _, registry := range c.ecrRegistries {
+		c.Logger.Infof("would log in to ECR registry %s in %s to %s", registry.Host, registry.Region, c.DockerConfig)
+	}
+
+	if c.codeArtifact != nil {
+		for _, repo := range c.codeArtifact.Repositories {
+			c.Logger.Infof("would configure %s for CodeArtifact repository %s/%s in %s",
+				repo.Format, c.codeArtifact.Domain, repo.Repository, c.CodeArtifactDir)
+		}
+	}
+
+	for _, cluster := range c.eksClusters {
+		c.Logger.Infof("would write EKS cluster %s in %s to the kubeconfig %s with %s authentication",
+			cluster.Name, cluster.Region, c.Kubeconfig, c.KubeconfigAuth)
+	}
+
+	if c.ScriptWrite {
+		c.Logger.Infof("would write the credentials as %s to %s", c.ScriptFormat, c.ScriptPath)
+	}
+
+	if c.Outputs != nil && c.Outputs.Write {
+		c.Logger.Infof("would write the session details to the outputs file %s", c.Outputs.Path)
+	}
+
+	if c.Outputs != nil && c.Outputs.WriteMasked {
+		c.Logger.Infof("would write the credentials to the masked outputs file %s", c.Outputs.MaskedPath)
+	}
+
+	if !c.DryRunToken {
+		if len(c.allowlists) > 0 {
+			c.Logger.Info("the allowlist is evaluated against the ID token, enable dry_run_token to evaluate it")
+		}
+
+		return nil
+	}
+
+	token, err := c.requestIDToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	claims, err := DecodeClaims(token)
+	if err != nil {
+		return newError(ErrorKindToken, err)
+	}
+
+	fields := logrus.Fields{}
+	for _, claim := range []string{"iss", "sub", "aud", "jti"} {
+		fields[claim] = claims[claim]
+	}
+
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		fields["exp"] = exp.UTC().Format(time.RFC3339)
+	}
+
+	c.Logger.WithFields(fields).Info("decoded ID token")
+
+	var failed []string
+
+	for _, f := range Diagnose(&DiagnoseInput{
+		Role:            c.AWS.Role,
+		Audience:        c.Audience,
+		Claims:          claims,
+		DurationSeconds: c.AWS.RoleDurationSeconds,
+	}) {
+		if !f.OK {
+			failed = append(failed, f.Check)
+
+			c.Logger.Warn(f.String())
+
+			continue
+		}
+
+		c.Logger.Info(f.String())
+	}
+
+	if len(failed) > 0 {
+		return newError(ErrorKindToken, fmt.Errorf("ID token failed the %s checks", strings.Join(failed, ", ")))
+	}
+
+	return c.enforceAllowlists(token)
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Exec_DryRun(t *testing.T) {
+	newTestSTS(t, func(_ http.ResponseWriter, _ url.Values) {
+		t.Error("a dry run must not call STS")
+	})
+
+	logger, hook := test.NewNullLogger()
+
+	c := &Config{
+		DryRun: true,
+		AWS: &AWS{
+			Region:              "us-east-1",
+			Role:                "arn:aws:iam::123456123456:role/test",
+			RoleDurationSeconds: 3600,
+			RoleSessionName:     "vela-{{ .Repo }}",
+		},
+		Build:        &BuildContext{Org: "octo-org", Repo: "octo-repo"},
+		Vela:         &Vela{},
+		Timeout:      &Timeout{},
+		ScriptFormat: ScriptFormatShell,
+		ScriptWrite:  true,
+		Logger:       logrus.NewEntry(logger),
+	}
+
+	assert.NoError(t, c.Validate(), "a dry run without the ID token should not require a request token")
+	assert.NoError(t, c.Exec(context.Background()))
+
+	var assumed *logrus.Entry
+
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "would assume role" {
+			assumed = entry
+		}
+	}
+
+	if assert.NotNil(t, assumed, "the role assumption should be explained") {
+		assert.Equal(t, "arn:aws:iam::123456123456:role/test", assumed.Data["role"])
+		assert.Equal(t, "vela-octo-repo", assumed.Data["session_name"])
+	}
+
+	assert.Nil(t, c.Session, "no session should be created")
+	assert.Equal(t, auditOutcomeDryRun, c.auditEvent(nil).Outcome)
+}
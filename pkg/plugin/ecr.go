@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+)
+
+var (
+	// ecrHostPattern represents the hostname of a private ECR registry.
+	ecrHostPattern = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+	// accountIDPattern represents an AWS account ID.
+	accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+)
+
+// ECRRegistry represents a private ECR registry to log in to.
+type ECRRegistry struct {
+	Host   string
+	Region string
+}
+
+// ParseECRRegistry parses a registry hostname, or an account ID of a registry
+// in the default region.
+func ParseECRRegistry(registry, defaultRegion string) (*ECRRegistry, error) {
+	if accountIDPattern.MatchString(registry) {
+		return &ECRRegistry{Host: ecrHost(registry, defaultRegion), Region: defaultRegion}, nil
+	}
+
+	match := ecrHostPattern.FindStringSubmatch(registry)
+	if match == nil {
+		return nil, fmt.Errorf("ECR registry %q must be an account ID or a registry hostname like 123456123456.dkr.ecr.us-east-1.amazonaws.com", registry)
+	}
+
+	return &ECRRegistry{Host: registry, Region: match[2]}, nil
+}
+
+// ecrHost returns the hostname of the private ECR registry of the account in the region.
+func ecrHost(accountID, region string) string {
+	host := fmt.Sprintf("%s.dkr.ecr.%s.amazonaws.com", accountID, region)
+	if strings.HasPrefix(region, "cn-") {
+		host += ".cn"
+	}
+
+	return host
+}
+
+// LoginECR requests an ECR authorization token for the region of each
+// registry and writes them as auths entries of the Docker config file,
+// keeping its other entries.
+func (c *Config) LoginECR(ctx context.Context, creds *aws.Credentials) error {
+	if len(c.ecrRegistries) == 0 {
+		return nil
+	}
+
+	// a single token grants access to every registry of its region
+	regions := map[string][]string{}
+	for _, registry := range c.ecrRegistries {
+		regions[registry.Region] = append(regions[registry.Region], registry.Host)
+	}
+
+	auths := map[string]string{}
+
+	var errs []error
+
+	for _, region := range sortedKeys(regions) {
+		cfg, err := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(credentials.StaticCredentialsProvider{Value: *creds}), config.WithRegion(region))
+		if err != nil {
+			return err
+		}
+
+		output, err := ecr.NewFromConfig(cfg).GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to get ECR authorization token for %s: %w", region, err))
+
+			continue
+		}
+
+		if len(output.AuthorizationData) == 0 {
+			errs = append(errs, fmt.Errorf("no ECR authorization token returned for %s", region))
+
+			continue
+		}
+
+		data := output.AuthorizationData[0]
+		token := aws.ToString(data.AuthorizationToken)
+
+		c.redact(token)
+
+		if decoded, err := base64.StdEncoding.DecodeString(token); err == nil {
+			_, password, _ := strings.Cut(string(decoded), ":")
+			c.redact(password)
+		}
+
+		for _, host := range regions[region] {
+			auths[host] = token
+		}
+
+		if data.ExpiresAt != nil {
+			c.Logger.Infof("logged in to ECR in %s until %s", region, data.ExpiresAt.UTC().Format("2006-01-02T15:04:05Z"))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	err := writeDockerConfig(c.DockerConfig, auths)
+	if err != nil {
+		return fmt.Errorf("unable to write Docker config: %w", err)
+	}
+
+	c.trackDockerAuths(c.DockerConfig, sortedKeys(auths))
+	c.Logger.Infof("wrote Docker config %s for %s", c.DockerConfig, strings.Join(sortedKeys(auths), ", "))
+
+	return nil
+}
+
+// removeDockerAuths removes the auths of the hosts from the Docker config file
+// at path, keeping its other settings and registries. The file is deleted when
+// nothing else is left in it, and is otherwise overwritten before it is
+// written again so the removed tokens do not remain on disk.
+func removeDockerAuths(path string, hosts []string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	dockerConfig := map[string]json.RawMessage{}
+	auths := map[string]json.RawMessage{}
+
+	err = json.Unmarshal(data, &dockerConfig)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+
+	if raw, ok := dockerConfig["auths"]; ok {
+		err = json.Unmarshal(raw, &auths)
+		if err != nil {
+			return fmt.Errorf("unable to parse auths of %s: %w", path, err)
+		}
+	}
+
+	for _, host := range hosts {
+		delete(auths, host)
+	}
+
+	delete(dockerConfig, "auths")
+
+	if len(auths) > 0 {
+		dockerConfig["auths"], err = json.Marshal(auths)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = shred(path)
+	if err != nil || len(dockerConfig) == 0 {
+		return err
+	}
+
+	data, err = json.MarshalIndent(dockerConfig, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return writeSecretFile(path, string(data)+"\n")
+}
+
+// writeDockerConfig merges the auths into the Docker config file at path,
+// keeping its other settings and registries, and makes it readable only by its owner.
+func writeDockerConfig(path string, auths map[string]string) error {
+	dockerConfig := map[string]json.RawMessage{}
+	existing := map[string]json.RawMessage{}
+
+	data, err := os.ReadFile(path)
+
+	switch {
+	case err == nil:
+		err = json.Unmarshal(data, &dockerConfig)
+		if err != nil {
+			return fmt.Errorf("unable to parse %s: %w", path, err)
+		}
+
+		if raw, ok := dockerConfig["auths"]; ok {
+			err = json.Unmarshal(raw, &existing)
+			if err != nil {
+				return fmt.Errorf("unable to parse auths of %s: %w", path, err)
+			}
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	for host, token := range auths {
+		entry, err := json.Marshal(map[string]string{"auth": token})
+		if err != nil {
+			return err
+		}
+
+		existing[host] = entry
+	}
+
+	dockerConfig["auths"], err = json.Marshal(existing)
+	if err != nil {
+		return err
+	}
+
+	data, err = json.MarshalIndent(dockerConfig, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return writeSecretFile(path, string(data)+"\n")
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseECRRegistry(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry string
+		want     *ECRRegistry
+		wantErr  string
+	}{
+		{
+			name:     "account ID",
+			registry: "123456123456",
+			want:     &ECRRegistry{Host: "123456123456.dkr.ecr.us-east-1.amazonaws.com", Region: "us-east-1"},
+		},
+		{
+			name:     "hostname",
+			registry: "123456123456.dkr.ecr.eu-west-1.amazonaws.com",
+			want:     &ECRRegistry{Host: "123456123456.dkr.ecr.eu-west-1.amazonaws.com", Region: "eu-west-1"},
+		},
+		{
+			name:     "fips hostname",
+			registry: "123456123456.dkr.ecr-fips.us-west-2.amazonaws.com",
+			want:     &ECRRegistry{Host: "123456123456.dkr.ecr-fips.us-west-2.amazonaws.com", Region: "us-west-2"},
+		},
+		{
+			name:     "china hostname",
+			registry: "123456123456.dkr.ecr.cn-north-1.amazonaws.com.cn",
+			want:     &ECRRegistry{Host: "123456123456.dkr.ecr.cn-north-1.amazonaws.com.cn", Region: "cn-north-1"},
+		},
+		{
+			name:     "public registry",
+			registry: "public.ecr.aws",
+			wantErr:  `ECR registry "public.ecr.aws" must be an account ID or a registry hostname`,
+		},
+		{
+			name:     "short account ID",
+			registry: "12345",
+			wantErr:  `ECR registry "12345" must be an account ID or a registry hostname`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseECRRegistry(test.registry, "us-east-1")
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestConfig_LoginECR(t *testing.T) {
+	var regions []string
+
+	newTestAWS(t, func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); !strings.HasSuffix(target, ".GetAuthorizationToken") {
+			t.Errorf("unexpected request %s", target)
+		}
+
+		// the region is part of the credential scope of the signature
+		region := strings.Split(r.Header.Get("Authorization"), "/")[2]
+		regions = append(regions, region)
+
+		token := base64.StdEncoding.EncodeToString([]byte("AWS:password-" + region))
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		fmt.Fprintf(w, `{"authorizationData": [{"authorizationToken": %q, "expiresAt": 1700000000, "proxyEndpoint": "https://registry"}]}`, token)
+	})
+
+	path := filepath.Join(t.TempDir(), "docker", "config.json")
+
+	err := writeSecretFile(path, `{"credsStore": "desktop", "auths": {"ghcr.io": {"auth": "Z2hjcg=="}}}`)
+	if err != nil {
+		t.Fatalf("unable to write Docker config: %v", err)
+	}
+
+	c := &Config{
+		DockerConfig: path,
+		Redactor:     NewRedactor(),
+		Logger:       logrus.NewEntry(logrus.StandardLogger()),
+		ecrRegistries: []ECRRegistry{
+			{Host: "123456123456.dkr.ecr.us-east-1.amazonaws.com", Region: "us-east-1"},
+			{Host: "654321654321.dkr.ecr.us-east-1.amazonaws.com", Region: "us-east-1"},
+			{Host: "123456123456.dkr.ecr.eu-west-1.amazonaws.com", Region: "eu-west-1"},
+		},
+	}
+
+	err = c.LoginECR(context.Background(), &aws.Credentials{AccessKeyID: "ACCESS_KEY_ID", SecretAccessKey: "SECRET_ACCESS_KEY"})
+	assert.NoError(t, err)
+
+	// a single token is requested for each region
+	assert.Equal(t, []string{"eu-west-1", "us-east-1"}, regions)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"credsStore": "desktop",
+		"auths": {
+			"ghcr.io": {"auth": "Z2hjcg=="},
+			"123456123456.dkr.ecr.us-east-1.amazonaws.com": {"auth": "QVdTOnBhc3N3b3JkLXVzLWVhc3QtMQ=="},
+			"654321654321.dkr.ecr.us-east-1.amazonaws.com": {"auth": "QVdTOnBhc3N3b3JkLXVzLWVhc3QtMQ=="},
+			"123456123456.dkr.ecr.eu-west-1.amazonaws.com": {"auth": "QVdTOnBhc3N3b3JkLWV1LXdlc3QtMQ=="}
+		}
+	}`, string(data))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the registries are recorded for the cleanup action and the tokens are redacted
+	assert.Empty(t, c.writtenFiles)
+	assert.Equal(t, map[string][]string{path: {
+		"123456123456.dkr.ecr.eu-west-1.amazonaws.com",
+		"123456123456.dkr.ecr.us-east-1.amazonaws.com",
+		"654321654321.dkr.ecr.us-east-1.amazonaws.com",
+	}}, c.dockerAuths)
+	assert.Equal(t, "[REDACTED] [REDACTED]", c.Redactor.Redact("QVdTOnBhc3N3b3JkLXVzLWVhc3QtMQ== password-eu-west-1"))
+}
+
+func TestConfig_LoginECR_Error(t *testing.T) {
+	newTestAWS(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"__type": "AccessDeniedException", "message": "not authorized to perform ecr:GetAuthorizationToken"}`)
+	})
+
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	c := &Config{
+		DockerConfig:  path,
+		Redactor:      NewRedactor(),
+		Logger:        logrus.NewEntry(logrus.StandardLogger()),
+		ecrRegistries: []ECRRegistry{{Host: "123456123456.dkr.ecr.us-east-1.amazonaws.com", Region: "us-east-1"}},
+	}
+
+	err := c.LoginECR(context.Background(), &aws.Credentials{AccessKeyID: "ACCESS_KEY_ID", SecretAccessKey: "SECRET_ACCESS_KEY"})
+	assert.ErrorContains(t, err, "unable to get ECR authorization token for us-east-1")
+	assert.NoFileExists(t, path)
+}
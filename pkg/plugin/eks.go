@@ -0,0 +1,342 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// eksTokenPrefix represents the prefix of the bearer tokens EKS accepts,
+	// followed by a base64url encoded presigned GetCallerIdentity URL.
+	eksTokenPrefix = "k8s-aws-v1."
+
+	// eksClusterIDHeader represents the signed header binding a token to its cluster.
+	eksClusterIDHeader = "x-k8s-aws-id"
+
+	// eksTokenExpirySeconds represents the validity of the presigned URL. EKS
+	// accepts the token for 15 minutes after it was signed regardless.
+	eksTokenExpirySeconds = "60"
+
+	// eksTokenLifetime represents how long EKS accepts a token after it was signed.
+	eksTokenLifetime = 15 * time.Minute
+
+	// execCredentialAPIVersion represents the API version of the client
+	// authentication exec plugin used by the kubeconfig.
+	execCredentialAPIVersion = "client.authentication.k8s.io/v1beta1"
+)
+
+// EKSCluster represents an EKS cluster to write to the kubeconfig.
+type EKSCluster struct {
+	// Name is the name or ARN of the cluster.
+	Name   string `yaml:"name"`
+	Region string `yaml:"region"`
+	// Context is the name of the cluster, user and context entries of the
+	// kubeconfig, the cluster name by default.
+	Context                  string `yaml:"context"`
+	Endpoint                 string `yaml:"endpoint"`
+	CertificateAuthorityData string `yaml:"certificate_authority_data"`
+}
+
+// ParseEKSClusters parses a YAML or JSON list of EKS clusters, rejecting
+// unknown keys. Clusters given by ARN take their region from it, and the others
+// default to the region.
+func ParseEKSClusters(data, defaultRegion string) ([]EKSCluster, error) {
+	var clusters []EKSCluster
+
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(&clusters)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse eks_clusters: %w", err)
+	}
+
+	var errs []error
+
+	contexts := map[string]bool{}
+
+	for i := range clusters {
+		cluster := &clusters[i]
+
+		if arn, ok := strings.CutPrefix(cluster.Name, "arn:"); ok {
+			// arn:partition:eks:region:account:cluster/name
+			parts := strings.SplitN(arn, ":", 5)
+			if len(parts) != 5 || parts[1] != "eks" || !strings.HasPrefix(parts[4], "cluster/") {
+				errs = append(errs, fmt.Errorf("EKS cluster %q is not a valid cluster ARN", cluster.Name))
+
+				continue
+			}
+
+			if cluster.Region != "" && cluster.Region != parts[2] {
+				errs = append(errs, fmt.Errorf("EKS cluster %s: region %s does not match the region of its ARN", cluster.Name, cluster.Region))
+			}
+
+			cluster.Name = strings.TrimPrefix(parts[4], "cluster/")
+			cluster.Region = parts[2]
+		}
+
+		if cluster.Name == "" {
+			errs = append(errs, fmt.Errorf("EKS cluster #%d has no name", i))
+
+			continue
+		}
+
+		if cluster.Region == "" {
+			cluster.Region = defaultRegion
+		}
+
+		if cluster.Context == "" {
+			cluster.Context = cluster.Name
+		}
+
+		if contexts[cluster.Context] {
+			errs = append(errs, fmt.Errorf("EKS cluster context %s is listed more than once", cluster.Context))
+		}
+
+		contexts[cluster.Context] = true
+
+		if (cluster.Endpoint == "") != (cluster.CertificateAuthorityData == "") {
+			errs = append(errs, fmt.Errorf("EKS cluster %s must have both or neither of endpoint and certificate_authority_data", cluster.Name))
+		}
+
+		if cluster.Endpoint != "" {
+			u, err := url.Parse(cluster.Endpoint)
+			if err != nil || u.Scheme != "https" || u.Host == "" {
+				errs = append(errs, fmt.Errorf("EKS cluster %s: endpoint %q must be an https URL", cluster.Name, cluster.Endpoint))
+			}
+		}
+
+		if cluster.CertificateAuthorityData != "" {
+			if _, err := base64.StdEncoding.DecodeString(cluster.CertificateAuthorityData); err != nil {
+				errs = append(errs, fmt.Errorf("EKS cluster %s: certificate_authority_data must be base64 encoded", cluster.Name))
+			}
+		}
+	}
+
+	return clusters, errors.Join(errs...)
+}
+
+// EKSToken returns a bearer token for the EKS cluster, made of a
+// GetCallerIdentity request presigned with the credentials of cfg, and the
+// time it expires.
+func EKSToken(ctx context.Context, cfg aws.Config, cluster string) (string, time.Time, error) {
+	signedAt := time.Now()
+
+	request, err := sts.NewPresignClient(sts.NewFromConfig(cfg)).PresignGetCallerIdentity(ctx, &sts.GetCallerIdentityInput{},
+		func(o *sts.PresignOptions) {
+			o.ClientOptions = append(o.ClientOptions, func(o *sts.Options) {
+				o.APIOptions = append(o.APIOptions, smithyhttp.AddHeaderValue(eksClusterIDHeader, cluster), addEKSTokenExpiry)
+			})
+		})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("unable to presign the EKS token of %s: %w", cluster, err)
+	}
+
+	token := eksTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(request.URL))
+
+	// expire early so clients refresh the token before EKS rejects it
+	return token, signedAt.Add(eksTokenLifetime - time.Minute), nil
+}
+
+// addEKSTokenExpiry sets the validity of the presigned GetCallerIdentity URL.
+func addEKSTokenExpiry(stack *middleware.Stack) error {
+	return stack.Build.Add(middleware.BuildMiddlewareFunc("EKSTokenExpiry",
+		func(ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler) (middleware.BuildOutput, middleware.Metadata, error) {
+			if req, ok := in.Request.(*smithyhttp.Request); ok {
+				query := req.URL.Query()
+				query.Set("X-Amz-Expires", eksTokenExpirySeconds)
+				req.URL.RawQuery = query.Encode()
+			}
+
+			return next.HandleBuild(ctx, in)
+		}), middleware.After)
+}
+
+type (
+	// kubeconfigYAML represents a kubeconfig file.
+	kubeconfigYAML struct {
+		APIVersion     string              `yaml:"apiVersion"`
+		Kind           string              `yaml:"kind"`
+		Clusters       []kubeconfigCluster `yaml:"clusters"`
+		Contexts       []kubeconfigContext `yaml:"contexts"`
+		Users          []kubeconfigUser    `yaml:"users"`
+		CurrentContext string              `yaml:"current-context"`
+		Preferences    map[string]any      `yaml:"preferences"`
+	}
+
+	// kubeconfigCluster represents a named cluster of a kubeconfig.
+	kubeconfigCluster struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+		} `yaml:"cluster"`
+	}
+
+	// kubeconfigContext represents a named context of a kubeconfig.
+	kubeconfigContext struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	}
+
+	// kubeconfigUser represents a named user of a kubeconfig, authenticating
+	// with a token or an exec plugin.
+	kubeconfigUser struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token string          `yaml:"token,omitempty"`
+			Exec  *kubeconfigExec `yaml:"exec,omitempty"`
+		} `yaml:"user"`
+	}
+
+	// kubeconfigExec represents a client authentication exec plugin.
+	kubeconfigExec struct {
+		APIVersion      string              `yaml:"apiVersion"`
+		Command         string              `yaml:"command"`
+		Args            []string            `yaml:"args"`
+		Env             []map[string]string `yaml:"env,omitempty"`
+		InteractiveMode string              `yaml:"interactiveMode"`
+	}
+)
+
+// WriteKubeconfig writes a kubeconfig for the EKS clusters, describing the
+// clusters without an endpoint, whose users authenticate with a presigned
+// token or by running this plugin as an exec plugin.
+func (c *Config) WriteKubeconfig(ctx context.Context, creds *aws.Credentials) error {
+	if len(c.eksClusters) == 0 {
+		return nil
+	}
+
+	kubeconfig := kubeconfigYAML{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		CurrentContext: c.eksClusters[0].Context,
+		Preferences:    map[string]any{},
+	}
+
+	var errs []error
+
+	for _, cluster := range c.eksClusters {
+		cfg, err := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(credentials.StaticCredentialsProvider{Value: *creds}), config.WithRegion(cluster.Region))
+		if err != nil {
+			return err
+		}
+
+		if cluster.Endpoint == "" {
+			output, err := eks.NewFromConfig(cfg).DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(cluster.Name)})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to describe EKS cluster %s: %w", cluster.Name, err))
+
+				continue
+			}
+
+			cluster.Endpoint = aws.ToString(output.Cluster.Endpoint)
+
+			if output.Cluster.CertificateAuthority != nil {
+				cluster.CertificateAuthorityData = aws.ToString(output.Cluster.CertificateAuthority.Data)
+			}
+		}
+
+		entry := kubeconfigCluster{Name: cluster.Context}
+		entry.Cluster.Server = cluster.Endpoint
+		entry.Cluster.CertificateAuthorityData = cluster.CertificateAuthorityData
+
+		kubeContext := kubeconfigContext{Name: cluster.Context}
+		kubeContext.Context.Cluster = cluster.Context
+		kubeContext.Context.User = cluster.Context
+
+		user := kubeconfigUser{Name: cluster.Context}
+
+		switch c.KubeconfigAuth {
+		case KubeconfigAuthExec:
+			user.User.Exec = c.kubeconfigExec(cluster)
+		default:
+			token, expiresAt, err := EKSToken(ctx, cfg, cluster.Name)
+			if err != nil {
+				errs = append(errs, err)
+
+				continue
+			}
+
+			c.redact(token)
+			c.Logger.Infof("generated EKS token for %s valid until %s", cluster.Name, expiresAt.UTC().Format("2006-01-02T15:04:05Z"))
+
+			user.User.Token = token
+		}
+
+		kubeconfig.Clusters = append(kubeconfig.Clusters, entry)
+		kubeconfig.Contexts = append(kubeconfig.Contexts, kubeContext)
+		kubeconfig.Users = append(kubeconfig.Users, user)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	err = writeSecretFile(c.Kubeconfig, string(data))
+	if err != nil {
+		return fmt.Errorf("unable to write kubeconfig: %w", err)
+	}
+
+	c.trackFile(c.Kubeconfig)
+	c.Logger.Infof("wrote kubeconfig %s for %d EKS cluster(s) with %s authentication", c.Kubeconfig, len(c.eksClusters), c.KubeconfigAuth)
+
+	return nil
+}
+
+// kubeconfigExec returns the exec plugin running the eks-token command of
+// this plugin. The command reads the credentials from the environment, or from
+// the credential file written by the plugin.
+func (c *Config) kubeconfigExec(cluster EKSCluster) *kubeconfigExec {
+	exec := &kubeconfigExec{
+		APIVersion:      execCredentialAPIVersion,
+		Command:         c.KubeExecCommand,
+		Args:            []string{"eks-token", "--cluster", cluster.Name, "--region", cluster.Region},
+		InteractiveMode: "Never",
+	}
+
+	if c.ScriptWrite && c.ScriptFormat == ScriptFormatCredentialFile {
+		exec.Env = []map[string]string{{"name": "AWS_SHARED_CREDENTIALS_FILE", "value": c.ScriptPath}}
+	}
+
+	return exec
+}
+
+// ExecCredential returns the client authentication exec plugin response
+// holding the EKS token.
+func ExecCredential(token string, expiresAt time.Time) map[string]any {
+	return map[string]any{
+		"kind":       "ExecCredential",
+		"apiVersion": execCredentialAPIVersion,
+		"spec":       map[string]any{},
+		"status": map[string]any{
+			"expirationTimestamp": expiresAt.UTC().Format(time.RFC3339),
+			"token":               token,
+		},
+	}
+}
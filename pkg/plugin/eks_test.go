@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.yaml.in/yaml/v3"
+)
+
+func TestParseEKSClusters(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []EKSCluster
+		wantErr string
+	}{
+		{
+			name: "names and ARNs",
+			data: `
+- name: staging
+- name: arn:aws:eks:eu-west-1:123456123456:cluster/production
+  context: prod
+- name: edge
+  region: us-west-2
+  endpoint: https://ABCDEF.gr7.us-west-2.eks.amazonaws.com
+  certificate_authority_data: Q0EK
+`,
+			want: []EKSCluster{
+				{Name: "staging", Region: "us-east-1", Context: "staging"},
+				{Name: "production", Region: "eu-west-1", Context: "prod"},
+				{
+					Name:                     "edge",
+					Region:                   "us-west-2",
+					Context:                  "edge",
+					Endpoint:                 "https://ABCDEF.gr7.us-west-2.eks.amazonaws.com",
+					CertificateAuthorityData: "Q0EK",
+				},
+			},
+		},
+		{
+			name:    "invalid ARN",
+			data:    `[{"name": "arn:aws:ecs:us-east-1:123456123456:cluster/production"}]`,
+			wantErr: `EKS cluster "arn:aws:ecs:us-east-1:123456123456:cluster/production" is not a valid cluster ARN`,
+		},
+		{
+			name:    "region mismatch",
+			data:    `[{"name": "arn:aws:eks:eu-west-1:123456123456:cluster/production", "region": "us-east-1"}]`,
+			wantErr: "region us-east-1 does not match the region of its ARN",
+		},
+		{
+			name:    "no name",
+			data:    `[{"region": "us-east-1"}]`,
+			wantErr: "EKS cluster #0 has no name",
+		},
+		{
+			name:    "duplicate context",
+			data:    `[{"name": "production"}, {"name": "arn:aws:eks:eu-west-1:123456123456:cluster/production"}]`,
+			wantErr: "EKS cluster context production is listed more than once",
+		},
+		{
+			name:    "endpoint without certificate authority",
+			data:    `[{"name": "edge", "endpoint": "https://ABCDEF.gr7.us-west-2.eks.amazonaws.com"}]`,
+			wantErr: "EKS cluster edge must have both or neither of endpoint and certificate_authority_data",
+		},
+		{
+			name:    "insecure endpoint",
+			data:    `[{"name": "edge", "endpoint": "http://edge", "certificate_authority_data": "Q0EK"}]`,
+			wantErr: `EKS cluster edge: endpoint "http://edge" must be an https URL`,
+		},
+		{
+			name:    "unknown key",
+			data:    `[{"cluster": "production"}]`,
+			wantErr: "field cluster not found",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseEKSClusters(test.data, "us-east-1")
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestEKSToken(t *testing.T) {
+	cfg := aws.Config{
+		Region:      "us-west-2",
+		Credentials: aws.CredentialsProviderFunc(testCredentials),
+	}
+
+	token, expiresAt, err := EKSToken(context.Background(), cfg, "production")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(14*time.Minute), expiresAt, time.Minute)
+
+	encoded, ok := strings.CutPrefix(token, "k8s-aws-v1.")
+	if !assert.True(t, ok, "token prefix") {
+		return
+	}
+
+	presigned, err := base64.RawURLEncoding.DecodeString(encoded)
+	assert.NoError(t, err)
+
+	u, err := url.Parse(string(presigned))
+	assert.NoError(t, err)
+	assert.Equal(t, "sts.us-west-2.amazonaws.com", u.Host)
+
+	query := u.Query()
+	assert.Equal(t, "GetCallerIdentity", query.Get("Action"))
+	assert.Equal(t, "60", query.Get("X-Amz-Expires"))
+	assert.Equal(t, "host;x-k8s-aws-id", query.Get("X-Amz-SignedHeaders"))
+	assert.True(t, strings.HasPrefix(query.Get("X-Amz-Credential"), "ACCESS_KEY_ID/"))
+}
+
+// testCredentials returns static credentials for tests that do not call AWS.
+func testCredentials(context.Context) (aws.Credentials, error) {
+	return aws.Credentials{AccessKeyID: "ACCESS_KEY_ID", SecretAccessKey: "SECRET_ACCESS_KEY"}, nil
+}
+
+func TestConfig_WriteKubeconfig(t *testing.T) {
+	var described []string
+
+	newTestAWS(t, func(w http.ResponseWriter, r *http.Request) {
+		described = append(described, r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"cluster": {"name": "staging", "endpoint": "https://STAGING.gr7.us-east-1.eks.amazonaws.com", "certificateAuthority": {"data": "U1RBR0lORwo="}}}`)
+	})
+
+	clusters, err := ParseEKSClusters(`
+- name: staging
+- name: edge
+  region: us-west-2
+  endpoint: https://EDGE.gr7.us-west-2.eks.amazonaws.com
+  certificate_authority_data: RURHRQo=
+`, "us-east-1")
+	if err != nil {
+		t.Fatalf("unable to parse clusters: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		auth      string
+		wantUsers func(t *testing.T, users []map[string]any)
+	}{
+		{
+			name: "token",
+			auth: KubeconfigAuthToken,
+			wantUsers: func(t *testing.T, users []map[string]any) {
+				for _, user := range users {
+					token, _ := user["user"].(map[string]any)["token"].(string)
+					assert.True(t, strings.HasPrefix(token, "k8s-aws-v1."), "token of %s", user["name"])
+				}
+			},
+		},
+		{
+			name: "exec",
+			auth: KubeconfigAuthExec,
+			wantUsers: func(t *testing.T, users []map[string]any) {
+				assert.Equal(t, map[string]any{
+					"apiVersion":      "client.authentication.k8s.io/v1beta1",
+					"command":         "vela-aws-credentials",
+					"args":            []any{"eks-token", "--cluster", "staging", "--region", "us-east-1"},
+					"env":             []any{map[string]any{"name": "AWS_SHARED_CREDENTIALS_FILE", "value": "/vela/secrets/aws/creds"}},
+					"interactiveMode": "Never",
+				}, users[0]["user"].(map[string]any)["exec"])
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			described = nil
+			path := filepath.Join(t.TempDir(), "kubeconfig")
+
+			c := &Config{
+				Kubeconfig:      path,
+				KubeconfigAuth:  test.auth,
+				KubeExecCommand: "vela-aws-credentials",
+				ScriptFormat:    ScriptFormatCredentialFile,
+				ScriptPath:      "/vela/secrets/aws/creds",
+				ScriptWrite:     true,
+				Redactor:        NewRedactor(),
+				Logger:          logrus.NewEntry(logrus.StandardLogger()),
+				eksClusters:     clusters,
+			}
+
+			err := c.WriteKubeconfig(context.Background(), &aws.Credentials{AccessKeyID: "ACCESS_KEY_ID", SecretAccessKey: "SECRET_ACCESS_KEY"})
+			assert.NoError(t, err)
+
+			// only the cluster without an endpoint is described
+			assert.Equal(t, []string{"/clusters/staging"}, described)
+
+			data, err := os.ReadFile(path)
+			assert.NoError(t, err)
+
+			var kubeconfig struct {
+				Clusters       []map[string]any `yaml:"clusters"`
+				Contexts       []map[string]any `yaml:"contexts"`
+				Users          []map[string]any `yaml:"users"`
+				CurrentContext string           `yaml:"current-context"`
+			}
+
+			err = yaml.Unmarshal(data, &kubeconfig)
+			assert.NoError(t, err)
+
+			assert.Equal(t, "staging", kubeconfig.CurrentContext)
+			assert.Equal(t, []map[string]any{
+				{"name": "staging", "cluster": map[string]any{"server": "https://STAGING.gr7.us-east-1.eks.amazonaws.com", "certificate-authority-data": "U1RBR0lORwo="}},
+				{"name": "edge", "cluster": map[string]any{"server": "https://EDGE.gr7.us-west-2.eks.amazonaws.com", "certificate-authority-data": "RURHRQo="}},
+			}, kubeconfig.Clusters)
+			assert.Equal(t, map[string]any{"cluster": "edge", "user": "edge"}, kubeconfig.Contexts[1]["context"])
+
+			if assert.Len(t, kubeconfig.Users, 2) {
+				test.wantUsers(t, kubeconfig.Users)
+			}
+
+			info, err := os.Stat(path)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+			assert.Equal(t, []string{path}, c.writtenFiles)
+		})
+	}
+}
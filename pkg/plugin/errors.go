@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/smithy-go"
+)
+
+// ErrorKind represents the category of a plugin failure.
+type ErrorKind int
+
+const (
+	// ErrorKindUnknown represents a failure that could not be classified.
+	ErrorKindUnknown ErrorKind = iota
+	// ErrorKindConfig represents an invalid plugin configuration.
+	ErrorKindConfig
+	// ErrorKindToken represents a failure to request or verify the ID token from Vela.
+	ErrorKindToken
+	// ErrorKindDenied represents the role assumption being denied by the plugin's role allowlist.
+	ErrorKindDenied
+	// ErrorKindAssumeRole represents a failure to assume the role that is not otherwise classified.
+	ErrorKindAssumeRole
+	// ErrorKindAccessDenied represents STS denying the role assumption.
+	ErrorKindAccessDenied
+	// ErrorKindInvalidToken represents STS rejecting the ID token.
+	ErrorKindInvalidToken
+	// ErrorKindExpiredToken represents STS rejecting the ID token as expired.
+	ErrorKindExpiredToken
+	// ErrorKindDurationTooLong represents a requested duration above the role's maximum session duration.
+	ErrorKindDurationTooLong
+	// ErrorKindMalformedPolicy represents an invalid session policy.
+	ErrorKindMalformedPolicy
+	// ErrorKindPolicyTooLarge represents session policies exceeding the packed size limit.
+	ErrorKindPolicyTooLarge
+	// ErrorKindVerify represents a failure to verify the assumed role credentials.
+	ErrorKindVerify
+	// ErrorKindSecrets represents a failure to fetch the secrets with the assumed role credentials.
+	ErrorKindSecrets
+	// ErrorKindLogin represents a failure to log in to a registry, package repository or cluster with the assumed role credentials.
+	ErrorKindLogin
+	// ErrorKindOutput represents a failure to write the plugin outputs.
+	ErrorKindOutput
+)
+
+// errorKind represents the name, exit code and remediation hint of an error kind.
+type errorKind struct {
+	name string
+	code int
+	hint string
+}
+
+// errorKinds represents the details of each error kind.
+var errorKinds = map[ErrorKind]errorKind{
+	ErrorKindUnknown: {
+		name: "unknown",
+		code: 1,
+	},
+	ErrorKindConfig: {
+		name: "configuration",
+		code: 2,
+		hint: "review the step parameters against the plugin documentation",
+	},
+	ErrorKindToken: {
+		name: "token",
+		code: 3,
+		hint: "make sure `id_request: yes` is set on the step and the Vela server is reachable; with verify_token, check the issuer and jwks_url parameters",
+	},
+	ErrorKindDenied: {
+		name: "denied",
+		code: 4,
+		hint: "the plugin's role allowlist does not allow this pipeline to assume the role; ask the Vela administrators to update the allowlist",
+	},
+	ErrorKindAssumeRole: {
+		name: "assume_role",
+		code: 10,
+		hint: "run `vela-aws-credentials diagnose` with the role and ID token to explain the failure",
+	},
+	ErrorKindAccessDenied: {
+		name: "access_denied",
+		code: 11,
+		hint: "the role's trust policy does not allow this pipeline; run `vela-aws-credentials diagnose` to see which sub or aud condition fails",
+	},
+	ErrorKindInvalidToken: {
+		name: "invalid_token",
+		code: 12,
+		hint: "check that an IAM OIDC provider exists for the Vela issuer and that its client ID list contains the audience parameter",
+	},
+	ErrorKindExpiredToken: {
+		name: "expired_token",
+		code: 13,
+		hint: "the ID token expired before it was exchanged; retry the build",
+	},
+	ErrorKindDurationTooLong: {
+		name: "duration_too_long",
+		code: 14,
+		hint: "lower role_duration_seconds or raise the role's MaxSessionDuration",
+	},
+	ErrorKindMalformedPolicy: {
+		name: "malformed_policy",
+		code: 15,
+		hint: "check inline_session_policy is a valid IAM policy document and managed_session_policies are valid policy ARNs",
+	},
+	ErrorKindPolicyTooLarge: {
+		name: "policy_too_large",
+		code: 16,
+		hint: "reduce the size of inline_session_policy or the number of managed_session_policies",
+	},
+	ErrorKindVerify: {
+		name: "verification",
+		code: 20,
+		hint: "the credentials were issued but could not be used; check the region and the role's permissions",
+	},
+	ErrorKindSecrets: {
+		name: "secrets",
+		code: 21,
+		hint: "the credentials were issued but a secret could not be fetched; check the secret names, the region and the role's permissions to read and decrypt them",
+	},
+	ErrorKindLogin: {
+		name: "login",
+		code: 22,
+		hint: "the credentials were issued but could not log in to a registry, package repository or cluster; check their names, regions and the role's permissions",
+	},
+	ErrorKindOutput: {
+		name: "output",
+		code: 30,
+		hint: "check that the script_path directory is writable",
+	},
+}
+
+// String returns the name of the error kind.
+func (k ErrorKind) String() string {
+	return errorKinds[k].name
+}
+
+// ExitCode returns the process exit code for the error kind.
+func (k ErrorKind) ExitCode() int {
+	return errorKinds[k].code
+}
+
+// Error represents a classified plugin failure with a remediation hint.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s error: %v", e.Kind, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Hint returns the remediation hint for the error.
+func (e *Error) Hint() string {
+	return errorKinds[e.Kind].hint
+}
+
+// newError classifies err as kind, leaving errors that are already classified untouched.
+func newError(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pluginErr *Error
+	if errors.As(err, &pluginErr) {
+		return err
+	}
+
+	return &Error{Kind: kind, Err: err}
+}
+
+// ExitCode returns the process exit code for err.
+func ExitCode(err error) int {
+	var pluginErr *Error
+	if errors.As(err, &pluginErr) {
+		return pluginErr.Kind.ExitCode()
+	}
+
+	return ErrorKindUnknown.ExitCode()
+}
+
+// classifySTSError classifies an error returned by AssumeRoleWithWebIdentity.
+func classifySTSError(err error) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return newError(ErrorKindAssumeRole, err)
+	}
+
+	kind := ErrorKindAssumeRole
+
+	switch apiErr.ErrorCode() {
+	case "AccessDenied":
+		kind = ErrorKindAccessDenied
+	case "InvalidIdentityToken":
+		kind = ErrorKindInvalidToken
+	case "ExpiredTokenException":
+		kind = ErrorKindExpiredToken
+	case "MalformedPolicyDocument":
+		kind = ErrorKindMalformedPolicy
+	case "PackedPolicyTooLarge":
+		kind = ErrorKindPolicyTooLarge
+	case "ValidationError":
+		if isDurationTooLongError(err) {
+			kind = ErrorKindDurationTooLong
+		}
+	}
+
+	return newError(kind, err)
+}
+
+// isDurationTooLongError reports whether STS rejected the requested duration
+// because it exceeds the role's maximum session duration.
+func isDurationTooLongError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	return apiErr.ErrorCode() == "ValidationError" && strings.Contains(apiErr.ErrorMessage(), "DurationSeconds")
+}
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlugin_classifySTSError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{
+			name: "access denied",
+			err:  &smithy.GenericAPIError{Code: "AccessDenied", Message: "Not authorized to perform sts:AssumeRoleWithWebIdentity"},
+			want: ErrorKindAccessDenied,
+		},
+		{
+			name: "invalid token",
+			err:  &smithy.GenericAPIError{Code: "InvalidIdentityToken", Message: "No OpenIDConnect provider found in your account"},
+			want: ErrorKindInvalidToken,
+		},
+		{
+			name: "expired token",
+			err:  &smithy.GenericAPIError{Code: "ExpiredTokenException", Message: "Token expired"},
+			want: ErrorKindExpiredToken,
+		},
+		{
+			name: "duration too long",
+			err:  &smithy.GenericAPIError{Code: "ValidationError", Message: "The requested DurationSeconds exceeds the MaxSessionDuration set for this role."},
+			want: ErrorKindDurationTooLong,
+		},
+		{
+			name: "other validation error",
+			err:  &smithy.GenericAPIError{Code: "ValidationError", Message: "1 validation error detected"},
+			want: ErrorKindAssumeRole,
+		},
+		{
+			name: "malformed policy",
+			err:  &smithy.GenericAPIError{Code: "MalformedPolicyDocument"},
+			want: ErrorKindMalformedPolicy,
+		},
+		{
+			name: "packed policy too large",
+			err:  &smithy.GenericAPIError{Code: "PackedPolicyTooLarge"},
+			want: ErrorKindPolicyTooLarge,
+		},
+		{
+			name: "wrapped unknown error",
+			err:  fmt.Errorf("failed to assume role: %w", errors.New("boom")),
+			want: ErrorKindAssumeRole,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifySTSError(fmt.Errorf("failed to assume role: %w", tt.err))
+
+			var pluginErr *Error
+			assert.ErrorAs(t, err, &pluginErr)
+			assert.Equal(t, tt.want, pluginErr.Kind)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.want.ExitCode(), ExitCode(err))
+		})
+	}
+}
+
+func TestPlugin_ExitCode(t *testing.T) {
+	assert.Equal(t, 1, ExitCode(errors.New("unclassified")))
+	assert.Equal(t, 2, ExitCode(newError(ErrorKindConfig, errors.New("no role provided"))))
+
+	// errors that are already classified keep their kind
+	err := newError(ErrorKindToken, newError(ErrorKindOutput, errors.New("write failed")))
+	assert.Equal(t, ErrorKindOutput.ExitCode(), ExitCode(err))
+
+	assert.NoError(t, newError(ErrorKindConfig, nil))
+}
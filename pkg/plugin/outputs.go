@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// output represents a single KEY=value line of a Vela outputs file.
+type output struct {
+	key   string
+	value string
+}
+
+// WriteOutputs appends the non-secret details of the session to the Vela
+// outputs file and, when enabled, the credentials to the masked outputs file.
+func (c *Config) WriteOutputs(creds *aws.Credentials) error {
+	if c.Outputs == nil {
+		return nil
+	}
+
+	if c.Outputs.Write {
+		outputs := []output{
+			{key: "AWS_REGION", value: c.AWS.Region},
+			{key: "AWS_ASSUMED_ROLE_ARN", value: c.Session.AssumedRoleARN},
+			{key: "AWS_ACCOUNT_ID", value: c.Session.AccountID},
+			{key: "AWS_ROLE_SESSION_NAME", value: c.Session.SessionName},
+			{key: "AWS_ROLE_DURATION_SECONDS", value: strconv.Itoa(c.Session.DurationSeconds)},
+		}
+
+		if creds.CanExpire {
+			outputs = append(outputs, output{key: "AWS_CREDENTIAL_EXPIRATION", value: creds.Expires.UTC().Format(time.RFC3339)})
+		}
+
+		if c.ScriptWrite {
+			outputs = append(outputs, output{key: "AWS_CREDENTIALS_PATH", value: c.ScriptPath})
+
+			if c.ScriptFormat == ScriptFormatCredentialFile {
+				outputs = append(outputs, output{key: "AWS_SHARED_CREDENTIALS_FILE", value: c.ScriptPath})
+			}
+		}
+
+		err := appendOutputs(c.Outputs.Path, outputs)
+		if err != nil {
+			return fmt.Errorf("unable to write outputs: %w", err)
+		}
+
+		c.Logger.Infof("wrote session details to outputs file %s", c.Outputs.Path)
+	}
+
+	if c.Outputs.WriteMasked {
+		outputs := []output{
+			{key: "AWS_ACCESS_KEY_ID", value: creds.AccessKeyID},
+			{key: "AWS_SECRET_ACCESS_KEY", value: creds.SecretAccessKey},
+			{key: "AWS_SESSION_TOKEN", value: creds.SessionToken},
+		}
+
+		err := appendOutputs(c.Outputs.MaskedPath, outputs)
+		if err != nil {
+			return fmt.Errorf("unable to write masked outputs: %w", err)
+		}
+
+		c.Logger.Infof("wrote credentials to masked outputs file %s", c.Outputs.MaskedPath)
+	}
+
+	return nil
+}
+
+// appendOutputs appends the outputs to the file at path, leaving outputs
+// written by earlier steps in place.
+func appendOutputs(path string, outputs []output) error {
+	var b strings.Builder
+
+	for _, o := range outputs {
+		if strings.ContainsAny(o.value, "\r\n") {
+			return fmt.Errorf("value of output %s contains a newline", o.key)
+		}
+
+		fmt.Fprintf(&b, "%s=%s\n", o.key, o.value)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(b.String())
+
+	return err
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/google/go-cmp/cmp"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_WriteOutputs(t *testing.T) {
+	dir := t.TempDir()
+	outputsPath := filepath.Join(dir, "base.env")
+	maskedPath := filepath.Join(dir, "masked.env")
+
+	err := os.WriteFile(outputsPath, []byte("EXISTING=value\n"), 0600)
+	if err != nil {
+		t.Fatalf("unable to write outputs file: %v", err)
+	}
+
+	c := &Config{
+		ScriptPath:   "/vela/secrets/aws/creds",
+		ScriptFormat: ScriptFormatCredentialFile,
+		ScriptWrite:  true,
+		AWS: &AWS{
+			Region: "us-east-1",
+		},
+		Outputs: &Outputs{
+			Write:       true,
+			WriteMasked: true,
+			Path:        outputsPath,
+			MaskedPath:  maskedPath,
+		},
+		Session: &Session{
+			AssumedRoleARN:  "arn:aws:sts::123456123456:assumed-role/test/vela",
+			AccountID:       "123456123456",
+			SessionName:     "vela",
+			DurationSeconds: 3600,
+		},
+		Logger: logrus.NewEntry(logrus.StandardLogger()),
+	}
+
+	creds := &aws.Credentials{
+		AccessKeyID:     "ACCESS_KEY_ID",
+		SecretAccessKey: "SECRET_ACCESS_KEY",
+		SessionToken:    "SESSION_TOKEN",
+		CanExpire:       true,
+		Expires:         time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	err = c.WriteOutputs(creds)
+	assert.NoError(t, err, "No error was expected")
+
+	for got, want := range map[string]string{
+		outputsPath: "testdata/outputs.env",
+		maskedPath:  "testdata/masked_outputs.env",
+	} {
+		expected, err := os.ReadFile(want)
+		assert.NoError(t, err)
+
+		actual, err := os.ReadFile(got)
+		assert.NoError(t, err)
+
+		if diff := cmp.Diff(string(expected), string(actual)); diff != "" {
+			t.Errorf("WriteOutputs() %s mismatch (-want +got):\n%s", want, diff)
+		}
+	}
+}
+
+func TestConfig_WriteOutputs_Disabled(t *testing.T) {
+	c := &Config{Outputs: &Outputs{}}
+
+	assert.NoError(t, c.WriteOutputs(&aws.Credentials{}))
+}
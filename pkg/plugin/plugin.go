@@ -3,64 +3,301 @@
 package plugin
 
 import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/sirupsen/logrus"
 )
 
 type (
 	// Config struct represents fields user can present to plugin.
 	Config struct {
-		Audience     string
-		Verify       bool
-		ScriptPath   string
-		ScriptFormat string
-		ScriptWrite  bool
-		AWS          *AWS
-		Vela         *Vela
-		Logger       *logrus.Entry
+		Action               string
+		Audience             string
+		DryRun               bool
+		DryRunToken          bool
+		Issuer               string
+		JWKSURL              string
+		Verify               bool
+		VerifyToken          bool
+		VerifyAccountID      string
+		VerifyAssumedRoleARN string
+		VerifyProbes         string
+		ScriptPath           string
+		ScriptFormat         string
+		ScriptWrite          bool
+		Allowlist            string
+		AllowlistFile        string
+		AuditFile            string
+		AuditWebhook         string
+		ManifestPath         string
+		Secrets              string
+		SecretsEnvFile       string
+		ECRRegistries        []string
+		DockerConfig         string
+		CodeArtifact         string
+		CodeArtifactDir      string
+		EKSClusters          string
+		Kubeconfig           string
+		KubeconfigAuth       string
+		KubeExecCommand      string
+		Outputs              *Outputs
+		Session              *Session
+		AWS                  *AWS
+		Build                *BuildContext
+		Vela                 *Vela
+		Retry                *Retry
+		Timeout              *Timeout
+		Logger               *logrus.Entry
+		Redactor             *Redactor
+
+		// verification probes parsed from VerifyProbes
+		verifyProbes []VerifyProbe
+
+		// roles resolved from the profile, from the role assumed with the web identity to the role of the profile
+		roleChain []ProfileRole
+
+		// allowlists parsed from AllowlistFile and Allowlist, by source
+		allowlists map[string]*Allowlist
+
+		// session policy read from InlineSessionPolicy or InlineSessionPolicyFile,
+		// rendered and normalized
+		inlineSessionPolicy string
+
+		// secrets parsed from Secrets
+		secrets []Secret
+
+		// registries parsed from ECRRegistries
+		ecrRegistries []ECRRegistry
+
+		// CodeArtifact domain and repositories parsed from CodeArtifact
+		codeArtifact *CodeArtifact
+
+		// clusters parsed from EKSClusters
+		eksClusters []EKSCluster
+
+		// files written by the plugin, recorded in the manifest for cleanup
+		writtenFiles []string
+
+		// registries logged in to by Docker config file, recorded in the manifest for cleanup
+		dockerAuths map[string][]string
+
+		// subject and ID of the ID token, recorded for auditing
+		tokenSubject string
+		tokenID      string
 	}
 
 	// AWS struct represents the config for the AWS role assumption.
 	AWS struct {
-		Region                 string
-		Role                   string
-		RoleDurationSeconds    int
-		RoleSessionName        string
-		InlineSessionPolicy    string
-		ManagedSessionPolicies []string
+		Region                  string
+		AdditionalRegions       []string
+		Role                    string
+		RoleDurationSeconds     int
+		DurationFallback        bool
+		DurationLadder          []int
+		RoleSessionName         string
+		InlineSessionPolicy     string
+		InlineSessionPolicyFile string
+		ManagedSessionPolicies  []string
+		RoleRules               string
+		RevokeRole              string
+		Profile                 string
+		ConfigFile              string
+	}
+
+	// Outputs struct represents the config for writing Vela step outputs.
+	Outputs struct {
+		Write       bool
+		WriteMasked bool
+		Path        string
+		MaskedPath  string
+	}
+
+	// Retry struct represents the retry policy for the Vela and STS API calls.
+	Retry struct {
+		Attempts  int
+		BaseDelay time.Duration
+		MaxDelay  time.Duration
+		Jitter    float64
+	}
+
+	// Timeout struct represents the overall and per-phase timeouts for the plugin.
+	Timeout struct {
+		Overall      time.Duration
+		Token        time.Duration
+		AssumeRole   time.Duration
+		Verify       time.Duration
+		Secrets      time.Duration
+		ECR          time.Duration
+		CodeArtifact time.Duration
+		Kubeconfig   time.Duration
+		Revoke       time.Duration
 	}
 
 	// Vela struct represents the config for the Vela API calls.
 	Vela struct {
-		BuildNumber     int
-		RepoName        string
-		OrgName         string
 		RequestToken    string
 		RequestTokenURL string
 	}
 )
 
+// requestIDToken requests the ID token from Vela, verifying it when enabled,
+// and records its subject and ID for auditing.
+func (c *Config) requestIDToken(ctx context.Context) (string, error) {
+	var token string
+
+	err := c.runPhase(ctx, phaseToken, c.Timeout.Token, func(ctx context.Context) error {
+		var err error
+
+		token, err = c.GenerateVelaToken(ctx)
+		if err != nil {
+			return err
+		}
+
+		c.redact(token)
+
+		if c.VerifyToken {
+			_, err = c.VerifyIDToken(ctx, token)
+			if err != nil {
+				return err
+			}
+		}
+
+		if claims, err := DecodeClaims(token); err == nil {
+			c.tokenSubject, _ = claims["sub"].(string)
+			c.tokenID, _ = claims["jti"].(string)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", newError(ErrorKindToken, err)
+	}
+
+	return token, nil
+}
+
 // Exec generates a set of temporary AWS credentials for later usage.
-func (c *Config) Exec() error {
+func (c *Config) Exec(ctx context.Context) error {
 	c.Logger.Debug("running plugin with provided configuration")
 
-	token, err := c.GenerateVelaToken()
+	if c.Timeout.Overall > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout.Overall)
+		defer cancel()
+	}
+
+	if c.Action == ActionCleanup {
+		return c.Cleanup(ctx)
+	}
+
+	if c.DryRun {
+		return c.runDryRun(ctx)
+	}
+
+	token, err := c.requestIDToken(ctx)
 	if err != nil {
 		return err
 	}
 
-	creds, err := c.AssumeRole(token)
+	err = c.enforceAllowlists(token)
 	if err != nil {
 		return err
 	}
 
+	var creds *aws.Credentials
+
+	err = c.runPhase(ctx, phaseAssumeRole, c.Timeout.AssumeRole, func(ctx context.Context) error {
+		var err error
+
+		creds, err = c.AssumeRole(ctx, token)
+
+		return err
+	})
+	if err != nil {
+		return classifySTSError(err)
+	}
+
+	c.redact(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken)
+
+	if c.Verify {
+		err = c.runPhase(ctx, phaseVerify, c.Timeout.Verify, func(ctx context.Context) error {
+			return c.VerifyCredentials(ctx, creds)
+		})
+		if err != nil {
+			return newError(ErrorKindVerify, err)
+		}
+	}
+
+	err = c.deliverCredentials(ctx, creds)
+	if err != nil {
+		return err
+	}
+
+	c.Logger.Debug("plugin finished...")
+
+	return nil
+}
+
+// deliverCredentials fetches the secrets and logins with the credentials and
+// writes them and the credentials to their files. The manifest is written
+// even when a step fails, so the cleanup action removes the files written
+// before the failure.
+func (c *Config) deliverCredentials(ctx context.Context, creds *aws.Credentials) (err error) {
+	defer func() {
+		manifestErr := c.WriteManifest()
+
+		switch {
+		case err == nil:
+			err = newError(ErrorKindOutput, manifestErr)
+		case manifestErr != nil:
+			c.Logger.Warnf("unable to write manifest: %v", manifestErr)
+		}
+	}()
+
+	err = c.runPhase(ctx, phaseSecrets, c.Timeout.Secrets, func(ctx context.Context) error {
+		return c.FetchSecrets(ctx, creds)
+	})
+	if err != nil {
+		return newError(ErrorKindSecrets, err)
+	}
+
+	err = c.runPhase(ctx, phaseECR, c.Timeout.ECR, func(ctx context.Context) error {
+		return c.LoginECR(ctx, creds)
+	})
+	if err != nil {
+		return newError(ErrorKindLogin, err)
+	}
+
+	err = c.runPhase(ctx, phaseCodeArtifact, c.Timeout.CodeArtifact, func(ctx context.Context) error {
+		return c.LoginCodeArtifact(ctx, creds)
+	})
+	if err != nil {
+		return newError(ErrorKindLogin, err)
+	}
+
+	err = c.runPhase(ctx, phaseKubeconfig, c.Timeout.Kubeconfig, func(ctx context.Context) error {
+		return c.WriteKubeconfig(ctx, creds)
+	})
+	if err != nil {
+		return newError(ErrorKindLogin, err)
+	}
+
 	if c.ScriptWrite {
 		err = c.WriteCreds(creds)
 		if err != nil {
-			return err
+			return newError(ErrorKindOutput, err)
 		}
+
+		c.trackFile(c.ScriptPath)
 	}
 
-	c.Logger.Debug("plugin finished...")
+	err = c.WriteOutputs(creds)
+	if err != nil {
+		return newError(ErrorKindOutput, err)
+	}
 
 	return nil
 }
@@ -0,0 +1,274 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// maxInlineSessionPolicyLength represents the largest inline session policy
+// STS accepts, in characters of the minified document.
+const maxInlineSessionPolicyLength = 2048
+
+type (
+	// PolicyDocument represents an IAM policy document.
+	PolicyDocument struct {
+		Version   string           `json:"Version,omitempty"`
+		Statement PolicyStatements `json:"Statement"`
+	}
+
+	// PolicyStatements represents the statements of an IAM policy document,
+	// which may be written as a single object or a list of objects.
+	PolicyStatements []PolicyStatement
+
+	// PolicyStatement represents a single statement of an IAM policy document.
+	PolicyStatement struct {
+		Sid         string                           `json:"Sid,omitempty"`
+		Effect      string                           `json:"Effect"`
+		Principal   Principal                        `json:"Principal,omitempty"`
+		Action      StringList                       `json:"Action,omitempty"`
+		NotAction   StringList                       `json:"NotAction,omitempty"`
+		Resource    StringList                       `json:"Resource,omitempty"`
+		NotResource StringList                       `json:"NotResource,omitempty"`
+		Condition   map[string]map[string]StringList `json:"Condition,omitempty"`
+	}
+
+	// Principal represents the principal of an IAM policy statement keyed by
+	// principal type (e.g. Federated, AWS, Service).
+	Principal map[string]StringList
+
+	// StringList represents an IAM policy value that may be written as a
+	// single string or a list of strings.
+	StringList []string
+)
+
+// UnmarshalJSON accepts either a single statement object or a list of statements.
+func (s *PolicyStatements) UnmarshalJSON(data []byte) error {
+	var single PolicyStatement
+
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = PolicyStatements{single}
+
+		return nil
+	}
+
+	var list []PolicyStatement
+
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("statement must be an object or a list of objects: %w", err)
+	}
+
+	*s = list
+
+	return nil
+}
+
+// UnmarshalJSON accepts either the "*" wildcard or a map of principal types.
+func (p *Principal) UnmarshalJSON(data []byte) error {
+	var wildcard string
+
+	if err := json.Unmarshal(data, &wildcard); err == nil {
+		*p = Principal{"AWS": StringList{wildcard}}
+
+		return nil
+	}
+
+	var m map[string]StringList
+
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("principal must be \"*\" or an object: %w", err)
+	}
+
+	*p = m
+
+	return nil
+}
+
+// UnmarshalJSON accepts either a single string or a list of strings.
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = StringList{single}
+
+		return nil
+	}
+
+	var list []string
+
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("value must be a string or a list of strings: %w", err)
+	}
+
+	*l = list
+
+	return nil
+}
+
+// MarshalJSON writes a list with a single value as a plain string. HTML
+// characters are left unescaped, so the encoder decides how to escape them.
+func (l StringList) MarshalJSON() ([]byte, error) {
+	var v any = []string(l)
+	if len(l) == 1 {
+		v = l[0]
+	}
+
+	buf := new(bytes.Buffer)
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	err := enc.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// ParsePolicyDocument parses a JSON IAM policy document.
+func ParsePolicyDocument(data []byte) (*PolicyDocument, error) {
+	doc := new(PolicyDocument)
+
+	err := json.Unmarshal(data, doc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse policy document: %w", err)
+	}
+
+	return doc, nil
+}
+
+// NormalizeSessionPolicy parses an inline session policy written as JSON or
+// YAML, validates it is an IAM policy document and returns it as minified JSON.
+func NormalizeSessionPolicy(raw string) (string, error) {
+	var (
+		node yaml.Node
+		doc  any
+	)
+
+	err := yaml.Unmarshal([]byte(raw), &node)
+	if err != nil {
+		return "", fmt.Errorf("inline session policy is not valid JSON or YAML: %w", err)
+	}
+
+	// keep unquoted dates such as the policy Version as strings
+	stringifyTimestamps(&node)
+
+	err = node.Decode(&doc)
+	if err != nil {
+		return "", fmt.Errorf("inline session policy is not valid JSON or YAML: %w", err)
+	}
+
+	if _, ok := doc.(map[string]any); !ok {
+		return "", fmt.Errorf("inline session policy must be a policy document object")
+	}
+
+	minified, err := json.Marshal(doc)
+	if err != nil {
+		return "", fmt.Errorf("unable to convert inline session policy to JSON: %w", err)
+	}
+
+	policy, err := ParsePolicyDocument(minified)
+	if err != nil {
+		return "", fmt.Errorf("inline session policy: %w", err)
+	}
+
+	err = policy.validateSessionPolicy()
+	if err != nil {
+		return "", err
+	}
+
+	if len(minified) > maxInlineSessionPolicyLength {
+		return "", fmt.Errorf("inline session policy is %d characters after minification, which exceeds the limit of %d characters",
+			len(minified), maxInlineSessionPolicyLength)
+	}
+
+	return string(minified), nil
+}
+
+// stringifyTimestamps retags YAML timestamps as strings so they are decoded verbatim.
+func stringifyTimestamps(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!timestamp" {
+		n.Tag = "!!str"
+	}
+
+	for _, child := range n.Content {
+		stringifyTimestamps(child)
+	}
+}
+
+// validateSessionPolicy validates the shape of a session policy document.
+func (d *PolicyDocument) validateSessionPolicy() error {
+	var errs []error
+
+	if d.Version != "" && d.Version != "2012-10-17" && d.Version != "2008-10-17" {
+		errs = append(errs, fmt.Errorf("inline session policy Version %q must be 2012-10-17", d.Version))
+	}
+
+	if len(d.Statement) == 0 {
+		errs = append(errs, fmt.Errorf("inline session policy has no Statement"))
+	}
+
+	for i, stmt := range d.Statement {
+		name := fmt.Sprintf("inline session policy statement %d", i)
+		if stmt.Sid != "" {
+			name = fmt.Sprintf("inline session policy statement %q", stmt.Sid)
+		}
+
+		if !slices.Contains([]string{"Allow", "Deny"}, stmt.Effect) {
+			errs = append(errs, fmt.Errorf("%s Effect %q must be Allow or Deny", name, stmt.Effect))
+		}
+
+		if len(stmt.Action) == 0 && len(stmt.NotAction) == 0 {
+			errs = append(errs, fmt.Errorf("%s must have an Action or NotAction", name))
+		}
+
+		if len(stmt.Resource) == 0 && len(stmt.NotResource) == 0 {
+			errs = append(errs, fmt.Errorf("%s must have a Resource or NotResource", name))
+		}
+
+		if len(stmt.Principal) > 0 {
+			errs = append(errs, fmt.Errorf("%s must not have a Principal in a session policy", name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// matchWildcard reports whether value matches pattern using IAM StringLike
+// semantics, where "*" matches any sequence of characters and "?" matches
+// any single character.
+func matchWildcard(pattern, value string) bool {
+	p, v := []rune(pattern), []rune(value)
+	pi, vi := 0, 0
+	star, match := -1, 0
+
+	for vi < len(v) {
+		switch {
+		case pi < len(p) && (p[pi] == '?' || p[pi] == v[vi]):
+			pi++
+			vi++
+		case pi < len(p) && p[pi] == '*':
+			star, match = pi, vi
+			pi++
+		case star != -1:
+			pi = star + 1
+			match++
+			vi = match
+		default:
+			return false
+		}
+	}
+
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+
+	return pi == len(p)
+}
@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlugin_NormalizeSessionPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "json",
+			policy: `{
+				"Version": "2012-10-17",
+				"Statement": [
+					{
+						"Effect": "Allow",
+						"Action": ["s3:GetObject"],
+						"Resource": "arn:aws:s3:::artifacts/*"
+					}
+				]
+			}`,
+			want: `{"Statement":[{"Action":["s3:GetObject"],"Effect":"Allow","Resource":"arn:aws:s3:::artifacts/*"}],"Version":"2012-10-17"}`,
+		},
+		{
+			name: "yaml",
+			policy: `
+Version: 2012-10-17
+Statement:
+  - Effect: Allow
+    Action: s3:GetObject
+    Resource: arn:aws:s3:::artifacts/*
+    Condition:
+      StringEquals:
+        aws:RequestedRegion: us-east-1
+`,
+			want: `{"Statement":[{"Action":"s3:GetObject","Condition":{"StringEquals":{"aws:RequestedRegion":"us-east-1"}},"Effect":"Allow","Resource":"arn:aws:s3:::artifacts/*"}],"Version":"2012-10-17"}`,
+		},
+		{
+			name:    "not a document",
+			policy:  `["s3:GetObject"]`,
+			wantErr: "must be a policy document object",
+		},
+		{
+			name:    "invalid yaml",
+			policy:  "Statement: [",
+			wantErr: "not valid JSON or YAML",
+		},
+		{
+			name:    "invalid effect",
+			policy:  `{"Version": "2012-10-17", "Statement": {"Effect": "allow", "Action": "s3:*", "Resource": "*"}}`,
+			wantErr: `Effect "allow" must be Allow or Deny`,
+		},
+		{
+			name:    "missing resource",
+			policy:  `{"Version": "2012-10-17", "Statement": [{"Sid": "Read", "Effect": "Allow", "Action": "s3:*"}]}`,
+			wantErr: `statement "Read" must have a Resource or NotResource`,
+		},
+		{
+			name:    "invalid version",
+			policy:  `{"Version": "2024-01-01", "Statement": {"Effect": "Allow", "Action": "s3:*", "Resource": "*"}}`,
+			wantErr: `Version "2024-01-01" must be 2012-10-17`,
+		},
+		{
+			name:    "statement with wrong shape",
+			policy:  `{"Version": "2012-10-17", "Statement": "s3:*"}`,
+			wantErr: "statement must be an object or a list of objects",
+		},
+		{
+			name: "too large",
+			policy: fmt.Sprintf(`{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "s3:*", "Resource": [%s]}}`,
+				strings.TrimSuffix(strings.Repeat(`"arn:aws:s3:::artifacts/octo-org/octo-repo/*",`, 50), ",")),
+			wantErr: "exceeds the limit of 2048 characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeSessionPolicy(tt.policy)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// maxChainedRoleDurationSeconds represents the longest duration STS allows for
+// a session assumed with the credentials of another role.
+const maxChainedRoleDurationSeconds = 3600
+
+type (
+	// Profile represents a named profile of an AWS shared config file,
+	// resolved to the roles to assume starting from the web identity.
+	Profile struct {
+		Name   string
+		Region string
+		// Roles are ordered from the role assumed with the web identity to
+		// the role of the profile.
+		Roles []ProfileRole
+	}
+
+	// ProfileRole represents a role to assume for a profile of the source_profile chain.
+	ProfileRole struct {
+		Profile         string
+		RoleARN         string
+		ExternalID      string
+		SessionName     string
+		DurationSeconds int
+	}
+)
+
+// parseSharedConfig parses the profiles of an AWS shared config file into
+// their settings. Nested settings, such as those of s3, are ignored.
+func parseSharedConfig(data []byte) (map[string]map[string]string, error) {
+	profiles := map[string]map[string]string{}
+
+	var section map[string]string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+
+		switch {
+		case trimmed == "", strings.HasPrefix(trimmed, "#"), strings.HasPrefix(trimmed, ";"):
+			continue
+		case strings.HasPrefix(trimmed, "["):
+			name, ok := strings.CutSuffix(trimmed, "]")
+			if !ok {
+				return nil, fmt.Errorf("line %d: invalid section %q", line, trimmed)
+			}
+
+			name = strings.Join(strings.Fields(strings.TrimPrefix(name, "[")), " ")
+
+			switch {
+			case name == "default":
+			case strings.HasPrefix(name, "profile "):
+				name = strings.TrimPrefix(name, "profile ")
+			default:
+				// sso-session and services sections do not describe profiles
+				section = nil
+
+				continue
+			}
+
+			section = map[string]string{}
+			profiles[name] = section
+		case text != strings.TrimLeft(text, " \t"):
+			// indented lines are nested settings
+			continue
+		default:
+			key, value, ok := strings.Cut(trimmed, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key = value", line)
+			}
+
+			if section != nil {
+				section[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	return profiles, scanner.Err()
+}
+
+// ResolveProfile resolves the named profile of an AWS shared config file by
+// following its source_profile chain. The web identity credentials take the
+// place of the credentials of the profile at the root of the chain, so the
+// first role is assumed with the ID token and every other role with the
+// credentials of the previous one.
+func ResolveProfile(data []byte, name string) (*Profile, error) {
+	profiles, err := parseSharedConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse AWS config file: %w", err)
+	}
+
+	settings, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %s not found in AWS config file", name)
+	}
+
+	profile := &Profile{Name: name, Region: settings["region"]}
+
+	var visited []string
+
+	for current := name; ; {
+		if slices.Contains(visited, current) {
+			return nil, fmt.Errorf("profile %s has a source_profile cycle: %s -> %s", name, strings.Join(visited, " -> "), current)
+		}
+
+		visited = append(visited, current)
+
+		settings, ok := profiles[current]
+		if !ok {
+			return nil, fmt.Errorf("source_profile %s of profile %s not found in AWS config file", current, visited[len(visited)-2])
+		}
+
+		if settings["role_arn"] == "" {
+			break
+		}
+
+		if settings["mfa_serial"] != "" {
+			return nil, fmt.Errorf("profile %s requires MFA which is not supported", current)
+		}
+
+		role := ProfileRole{
+			Profile:     current,
+			RoleARN:     settings["role_arn"],
+			ExternalID:  settings["external_id"],
+			SessionName: settings["role_session_name"],
+		}
+
+		if duration := settings["duration_seconds"]; duration != "" {
+			role.DurationSeconds, err = strconv.Atoi(duration)
+			if err != nil {
+				return nil, fmt.Errorf("profile %s has an invalid duration_seconds %q", current, duration)
+			}
+		}
+
+		profile.Roles = append([]ProfileRole{role}, profile.Roles...)
+
+		// a profile sourcing itself takes its credentials from the root
+		source := settings["source_profile"]
+		if source == "" || source == current {
+			break
+		}
+
+		current = source
+	}
+
+	if len(profile.Roles) == 0 {
+		return nil, fmt.Errorf("profile %s has no role_arn", name)
+	}
+
+	return profile, nil
+}
+
+// applyProfile resolves the profile from the AWS config file, taking the role,
+// region, duration and session name from it and recording the roles to
+// assume before it.
+func (c *Config) applyProfile() error {
+	if c.AWS.Profile == "" {
+		return nil
+	}
+
+	if c.AWS.Role != "" || c.AWS.RoleRules != "" {
+		return fmt.Errorf("profile may not be combined with role or role_rules")
+	}
+
+	path := c.AWS.ConfigFile
+	if path == "" {
+		path = config.DefaultSharedConfigFilename()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read AWS config file: %w", err)
+	}
+
+	profile, err := ResolveProfile(data, c.AWS.Profile)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+
+	for i, role := range profile.Roles {
+		c.redact(role.ExternalID)
+
+		if _, err := ParseRoleARN(role.RoleARN); err != nil {
+			errs = append(errs, fmt.Errorf("profile %s: %w", role.Profile, err))
+		}
+
+		// the root is assumed with the ID token, which does not take an external ID
+		if i == 0 && role.ExternalID != "" {
+			errs = append(errs, fmt.Errorf("profile %s: external_id is not supported on the root role of the chain, which is assumed with the ID token", role.Profile))
+		}
+
+		// sessions assumed with the credentials of another role are limited to an hour
+		if i > 0 && role.DurationSeconds > maxChainedRoleDurationSeconds {
+			errs = append(errs, fmt.Errorf("profile %s: duration_seconds %d exceeds the %ds allowed for chained roles",
+				role.Profile, role.DurationSeconds, maxChainedRoleDurationSeconds))
+		}
+	}
+
+	leaf := profile.Roles[len(profile.Roles)-1]
+
+	c.AWS.Role = leaf.RoleARN
+
+	if profile.Region != "" {
+		c.AWS.Region = profile.Region
+	}
+
+	if leaf.DurationSeconds > 0 {
+		c.AWS.RoleDurationSeconds = leaf.DurationSeconds
+	}
+
+	if leaf.SessionName != "" {
+		c.AWS.RoleSessionName = leaf.SessionName
+	}
+
+	if len(profile.Roles) > 1 && c.AWS.RoleDurationSeconds > maxChainedRoleDurationSeconds {
+		errs = append(errs, fmt.Errorf("role duration %d exceeds the %ds allowed for the chained role of profile %s",
+			c.AWS.RoleDurationSeconds, maxChainedRoleDurationSeconds, profile.Name))
+	}
+
+	c.roleChain = profile.Roles
+
+	c.Logger.Infof("resolved profile %s to role %s through %d role(s)", profile.Name, c.AWS.Role, len(profile.Roles))
+
+	return errors.Join(errs...)
+}
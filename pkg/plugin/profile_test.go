@@ -0,0 +1,290 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// stsAssumeRoleResponse represents an AssumeRole response from STS for the role and session name.
+const stsAssumeRoleResponse = `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>CHAINED_ACCESS_KEY_ID</AccessKeyId>
+      <SecretAccessKey>CHAINED_SECRET_ACCESS_KEY</SecretAccessKey>
+      <SessionToken>CHAINED_SESSION_TOKEN</SessionToken>
+      <Expiration>2030-01-01T00:00:00Z</Expiration>
+    </Credentials>
+    <AssumedRoleUser>
+      <Arn>%s</Arn>
+      <AssumedRoleId>AROAEXAMPLE:vela</AssumedRoleId>
+    </AssumedRoleUser>
+  </AssumeRoleResult>
+</AssumeRoleResponse>`
+
+func TestResolveProfile(t *testing.T) {
+	data, err := os.ReadFile("testdata/aws_config")
+	if err != nil {
+		t.Fatalf("unable to read AWS config file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		want    *Profile
+		wantErr string
+	}{
+		{
+			name:    "single role",
+			profile: "standalone",
+			want: &Profile{
+				Name: "standalone",
+				Roles: []ProfileRole{
+					{Profile: "standalone", RoleARN: "arn:aws:iam::444444444444:role/standalone", SessionName: "standalone", DurationSeconds: 7200},
+				},
+			},
+		},
+		{
+			name:    "source_profile chain",
+			profile: "deploy",
+			want: &Profile{
+				Name:   "deploy",
+				Region: "us-west-2",
+				Roles: []ProfileRole{
+					{Profile: "vela", RoleARN: "arn:aws:iam::111111111111:role/vela", DurationSeconds: 900},
+					{Profile: "tools", RoleARN: "arn:aws:iam::222222222222:role/tools", SessionName: "vela-tools"},
+					{Profile: "deploy", RoleARN: "arn:aws:iam::333333333333:role/deploy", ExternalID: "deploy-external-id"},
+				},
+			},
+		},
+		{
+			name:    "source profile without a role is the web identity",
+			profile: "laptop",
+			want: &Profile{
+				Name:  "laptop",
+				Roles: []ProfileRole{{Profile: "laptop", RoleARN: "arn:aws:iam::666666666666:role/laptop"}},
+			},
+		},
+		{
+			name:    "profile sourcing itself",
+			profile: "self",
+			want: &Profile{
+				Name:  "self",
+				Roles: []ProfileRole{{Profile: "self", RoleARN: "arn:aws:iam::555555555555:role/self"}},
+			},
+		},
+		{
+			name:    "cycle",
+			profile: "loop-a",
+			wantErr: "profile loop-a has a source_profile cycle: loop-a -> loop-b -> loop-a",
+		},
+		{
+			name:    "missing source profile",
+			profile: "missing-source",
+			wantErr: "source_profile nope of profile missing-source not found in AWS config file",
+		},
+		{
+			name:    "missing profile",
+			profile: "nope",
+			wantErr: "profile nope not found in AWS config file",
+		},
+		{
+			name:    "no role",
+			profile: "default",
+			wantErr: "profile default has no role_arn",
+		},
+		{
+			name:    "mfa",
+			profile: "mfa",
+			wantErr: "profile mfa requires MFA which is not supported",
+		},
+		{
+			name:    "sso sessions are not profiles",
+			profile: "corp",
+			wantErr: "profile corp not found in AWS config file",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ResolveProfile(data, test.profile)
+			if test.wantErr != "" {
+				assert.EqualError(t, err, test.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestConfig_applyProfile(t *testing.T) {
+	tests := []struct {
+		name         string
+		profile      string
+		role         string
+		wantRole     string
+		wantRegion   string
+		wantDuration int
+		wantSession  string
+		wantErr      string
+	}{
+		{
+			name:         "profile settings",
+			profile:      "standalone",
+			wantRole:     "arn:aws:iam::444444444444:role/standalone",
+			wantRegion:   "us-east-1",
+			wantDuration: 7200,
+			wantSession:  "standalone",
+		},
+		{
+			name:         "chained profile",
+			profile:      "deploy",
+			wantRole:     "arn:aws:iam::333333333333:role/deploy",
+			wantRegion:   "us-west-2",
+			wantDuration: 3600,
+			wantSession:  "vela",
+		},
+		{
+			name:    "chained duration too long",
+			profile: "long-chain",
+			wantErr: "profile long-chain: duration_seconds 7200 exceeds the 3600s allowed for chained roles",
+		},
+		{
+			name:    "external ID on the root role",
+			profile: "external-root",
+			wantErr: "profile external-root: external_id is not supported on the root role of the chain",
+		},
+		{
+			name:    "combined with role",
+			profile: "deploy",
+			role:    "arn:aws:iam::123456123456:role/test",
+			wantErr: "profile may not be combined with role or role_rules",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &Config{
+				AWS: &AWS{
+					Profile:             test.profile,
+					ConfigFile:          "testdata/aws_config",
+					Role:                test.role,
+					Region:              "us-east-1",
+					RoleDurationSeconds: 3600,
+					RoleSessionName:     "vela",
+				},
+				Logger: logrus.NewEntry(logrus.StandardLogger()),
+			}
+
+			err := c.applyProfile()
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantRole, c.AWS.Role)
+			assert.Equal(t, test.wantRegion, c.AWS.Region)
+			assert.Equal(t, test.wantDuration, c.AWS.RoleDurationSeconds)
+			assert.Equal(t, test.wantSession, c.AWS.RoleSessionName)
+		})
+	}
+}
+
+func TestConfig_AssumeRole_Profile(t *testing.T) {
+	var got []url.Values
+
+	newTestSTS(t, func(w http.ResponseWriter, form url.Values) {
+		got = append(got, form)
+
+		switch form.Get("Action") {
+		case "AssumeRoleWithWebIdentity":
+			fmt.Fprint(w, stsCredentialsResponse)
+		case "AssumeRole":
+			fmt.Fprintf(w, stsAssumeRoleResponse, "arn:aws:sts::333333333333:assumed-role/deploy/vela")
+		}
+	})
+
+	c := &Config{
+		AWS: &AWS{
+			Profile:                "deploy",
+			ConfigFile:             "testdata/aws_config",
+			Region:                 "us-east-1",
+			RoleDurationSeconds:    3600,
+			RoleSessionName:        "vela",
+			ManagedSessionPolicies: []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+		},
+		Redactor:            NewRedactor(),
+		Logger:              logrus.NewEntry(logrus.StandardLogger()),
+		inlineSessionPolicy: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:*","Resource":"*"}]}`,
+	}
+
+	assert.NoError(t, c.applyProfile())
+
+	creds, err := c.AssumeRole(context.Background(), "token")
+	assert.NoError(t, err)
+
+	if assert.Len(t, got, 3) {
+		// the web identity assumes the root of the chain without session policies
+		assert.Equal(t, "AssumeRoleWithWebIdentity", got[0].Get("Action"))
+		assert.Equal(t, "arn:aws:iam::111111111111:role/vela", got[0].Get("RoleArn"))
+		assert.Equal(t, "900", got[0].Get("DurationSeconds"))
+		assert.Empty(t, got[0].Get("Policy"))
+
+		assert.Equal(t, "AssumeRole", got[1].Get("Action"))
+		assert.Equal(t, "arn:aws:iam::222222222222:role/tools", got[1].Get("RoleArn"))
+		assert.Equal(t, "vela-tools", got[1].Get("RoleSessionName"))
+		assert.Equal(t, "900", got[1].Get("DurationSeconds"))
+		assert.Empty(t, got[1].Get("ExternalId"))
+		assert.Empty(t, got[1].Get("Policy"))
+
+		// the session policies scope down the role of the profile
+		assert.Equal(t, "AssumeRole", got[2].Get("Action"))
+		assert.Equal(t, "arn:aws:iam::333333333333:role/deploy", got[2].Get("RoleArn"))
+		assert.Equal(t, "vela", got[2].Get("RoleSessionName"))
+		assert.Equal(t, "3600", got[2].Get("DurationSeconds"))
+		assert.Equal(t, "deploy-external-id", got[2].Get("ExternalId"))
+		assert.Equal(t, c.inlineSessionPolicy, got[2].Get("Policy"))
+		assert.Equal(t, "arn:aws:iam::aws:policy/ReadOnlyAccess", got[2].Get("PolicyArns.member.1.arn"))
+	}
+
+	assert.Equal(t, "CHAINED_ACCESS_KEY_ID", creds.AccessKeyID)
+	assert.Equal(t, "arn:aws:sts::333333333333:assumed-role/deploy/vela", c.Session.AssumedRoleARN)
+	assert.Equal(t, "333333333333", c.Session.AccountID)
+	assert.Equal(t, 3600, c.Session.DurationSeconds)
+
+	// the external ID and the credentials of the intermediate roles are redacted
+	assert.Equal(t, "[REDACTED] [REDACTED]", c.Redactor.Redact("deploy-external-id SECRET_ACCESS_KEY"))
+}
+
+func TestConfig_chainSessionName(t *testing.T) {
+	c := &Config{
+		AWS: &AWS{RoleSessionName: "deploy session"},
+		roleChain: []ProfileRole{
+			{Profile: "vela"},
+			{Profile: "tools", SessionName: "vela tools"},
+			{Profile: "deploy", SessionName: "deploy session"},
+		},
+	}
+
+	assert.NoError(t, c.loadRoleSessionName())
+
+	assert.Equal(t, "deploy-session", c.AWS.RoleSessionName)
+	assert.Equal(t, "deploy-session", c.chainSessionName(0))
+	assert.Equal(t, "vela-tools", c.chainSessionName(1))
+
+	// the role of the profile uses the same session name as the verified identity
+	assert.Equal(t, c.AWS.RoleSessionName, c.chainSessionName(2))
+}
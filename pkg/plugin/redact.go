@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redacted represents the value that replaces secrets in log output.
+const redacted = "[REDACTED]"
+
+// Redactor is a logrus hook that replaces known secret values in the message
+// and fields of every log entry. Vela masks the secrets it injects, but not the
+// tokens and credentials minted by the plugin itself.
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  []string
+	replacer *strings.Replacer
+}
+
+// NewRedactor returns a Redactor with no known secrets.
+func NewRedactor() *Redactor {
+	return &Redactor{replacer: strings.NewReplacer()}
+}
+
+// Add registers secret values to redact. Empty values are ignored.
+func (r *Redactor) Add(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, secret := range secrets {
+		if secret == "" || slices.Contains(r.secrets, secret) {
+			continue
+		}
+
+		r.secrets = append(r.secrets, secret)
+	}
+
+	// replace longer secrets first so a secret containing another is fully redacted
+	slices.SortFunc(r.secrets, func(a, b string) int {
+		return cmp.Compare(len(b), len(a))
+	})
+
+	pairs := make([]string, 0, 2*len(r.secrets))
+	for _, secret := range r.secrets {
+		pairs = append(pairs, secret, redacted)
+	}
+
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// Redact returns s with every known secret replaced.
+func (r *Redactor) Redact(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.replacer.Replace(s)
+}
+
+// Levels returns the log levels the Redactor applies to, which is all of them.
+func (r *Redactor) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire redacts known secrets from the message and fields of the entry before it is formatted.
+func (r *Redactor) Fire(entry *logrus.Entry) error {
+	entry.Message = r.Redact(entry.Message)
+
+	for key, value := range entry.Data {
+		switch v := value.(type) {
+		case string:
+			entry.Data[key] = r.Redact(v)
+		case int, int32, int64, bool, float64:
+		default:
+			s := fmt.Sprint(v)
+			if redactedValue := r.Redact(s); redactedValue != s {
+				entry.Data[key] = redactedValue
+			}
+		}
+	}
+
+	return nil
+}
+
+// redact registers secret values with the Redactor of the plugin, when configured.
+func (c *Config) redact(secrets ...string) {
+	if c.Redactor != nil {
+		c.Redactor.Add(secrets...)
+	}
+}
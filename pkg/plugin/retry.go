@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/sirupsen/logrus"
+)
+
+// retryableSTSErrorCodes represents the STS error codes that are safe to retry.
+var retryableSTSErrorCodes = []string{
+	"IDPCommunicationError",
+	"InternalFailure",
+	"RequestLimitExceeded",
+	"RequestTimeout",
+	"ServiceUnavailable",
+	"Throttling",
+	"ThrottlingException",
+}
+
+// httpStatusError represents an unsuccessful HTTP response from an API.
+type httpStatusError struct {
+	StatusCode int
+	Err        error
+}
+
+// Error returns the error message of the response.
+func (e *httpStatusError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("unexpected status code %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("unexpected status code %d: %v", e.StatusCode, e.Err)
+}
+
+// Unwrap returns the underlying error of the response.
+func (e *httpStatusError) Unwrap() error {
+	return e.Err
+}
+
+// Do calls fn until it succeeds, returns an error that is not retryable or
+// the configured number of attempts is exhausted. A nil policy calls fn once.
+func (r *Retry) Do(ctx context.Context, logger *logrus.Entry, name string, retryable func(error) bool, fn func(context.Context) error) error {
+	attempts := 1
+	if r != nil && r.Attempts > 1 {
+		attempts = r.Attempts
+	}
+
+	var err error
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		logger.WithFields(logrus.Fields{
+			"attempt":  attempt,
+			"attempts": attempts,
+		}).Debugf("calling %s", name)
+
+		err = fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if attempt == attempts || !retryable(err) || ctx.Err() != nil {
+			break
+		}
+
+		delay := r.delay(attempt)
+
+		logger.WithFields(logrus.Fields{
+			"attempt":  attempt,
+			"attempts": attempts,
+			"delay":    delay.String(),
+		}).Warnf("%s failed with retryable error, retrying: %v", name, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w", name, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+
+	return err
+}
+
+// delay returns the exponential backoff delay before the next attempt.
+func (r *Retry) delay(attempt int) time.Duration {
+	d := r.BaseDelay << (attempt - 1)
+	if d <= 0 || (r.MaxDelay > 0 && d > r.MaxDelay) {
+		d = r.MaxDelay
+	}
+
+	if r.Jitter > 0 {
+		//nolint:gosec // jitter does not require a cryptographically secure random number
+		d -= time.Duration(rand.Float64() * r.Jitter * float64(d))
+	}
+
+	return d
+}
+
+// isRetryableSTSError reports whether an STS call that failed with err may be retried.
+func isRetryableSTSError(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return slices.Contains(retryableSTSErrorCodes, apiErr.ErrorCode())
+	}
+
+	return isRetryableNetworkError(err)
+}
+
+// retryableIAMErrorCodes represents the IAM error codes that are safe to retry.
+var retryableIAMErrorCodes = []string{
+	"ServiceFailure",
+	"Throttling",
+}
+
+// isRetryableIAMError reports whether an IAM call that failed with err may be retried.
+func isRetryableIAMError(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return slices.Contains(retryableIAMErrorCodes, apiErr.ErrorCode())
+	}
+
+	return isRetryableNetworkError(err)
+}
+
+// isRetryableVelaError reports whether a Vela API call that failed with err may be retried.
+func isRetryableVelaError(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	return isRetryableNetworkError(err)
+}
+
+// isRetryableNetworkError reports whether err is a transient network failure.
+func isRetryableNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr)
+}
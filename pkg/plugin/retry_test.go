@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry_Do(t *testing.T) {
+	errRetryable := errors.New("retryable")
+	errTerminal := errors.New("terminal")
+
+	retryable := func(err error) bool { return errors.Is(err, errRetryable) }
+
+	tests := []struct {
+		name      string
+		retry     *Retry
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "succeeds first attempt",
+			retry:     &Retry{Attempts: 3},
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			name:      "succeeds after retryable errors",
+			retry:     &Retry{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Jitter: 0.5},
+			errs:      []error{errRetryable, errRetryable, nil},
+			wantCalls: 3,
+		},
+		{
+			name:      "stops on terminal error",
+			retry:     &Retry{Attempts: 3, BaseDelay: time.Millisecond},
+			errs:      []error{errTerminal, nil},
+			wantCalls: 1,
+			wantErr:   errTerminal,
+		},
+		{
+			name:      "exhausts attempts",
+			retry:     &Retry{Attempts: 2, BaseDelay: time.Millisecond},
+			errs:      []error{errRetryable, errRetryable, nil},
+			wantCalls: 2,
+			wantErr:   errRetryable,
+		},
+		{
+			name:      "nil policy calls once",
+			errs:      []error{errRetryable, nil},
+			wantCalls: 1,
+			wantErr:   errRetryable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+
+			err := tt.retry.Do(context.Background(), logrus.NewEntry(logrus.StandardLogger()), "test", retryable, func(context.Context) error {
+				err := tt.errs[calls]
+				calls++
+
+				return err
+			})
+
+			assert.Equal(t, tt.wantCalls, calls)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRetry_delay(t *testing.T) {
+	r := &Retry{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	assert.Equal(t, time.Second, r.delay(1))
+	assert.Equal(t, 2*time.Second, r.delay(2))
+	assert.Equal(t, 4*time.Second, r.delay(3))
+	assert.Equal(t, 5*time.Second, r.delay(4))
+	assert.Equal(t, 5*time.Second, r.delay(100))
+
+	r.Jitter = 1
+
+	for attempt := 1; attempt < 10; attempt++ {
+		assert.LessOrEqual(t, r.delay(attempt), 5*time.Second)
+	}
+}
+
+func TestPlugin_isRetryableError(t *testing.T) {
+	tests := []struct {
+		name      string
+		retryable func(error) bool
+		err       error
+		want      bool
+	}{
+		{"sts idp communication error", isRetryableSTSError, &smithy.GenericAPIError{Code: "IDPCommunicationError"}, true},
+		{"sts throttling", isRetryableSTSError, &smithy.GenericAPIError{Code: "Throttling"}, true},
+		{"sts access denied", isRetryableSTSError, &smithy.GenericAPIError{Code: "AccessDenied"}, false},
+		{"sts invalid token", isRetryableSTSError, &smithy.GenericAPIError{Code: "InvalidIdentityToken"}, false},
+		{"sts network error", isRetryableSTSError, &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"sts canceled", isRetryableSTSError, context.Canceled, false},
+		{"iam service failure", isRetryableIAMError, &smithy.GenericAPIError{Code: "ServiceFailure"}, true},
+		{"iam no such entity", isRetryableIAMError, &smithy.GenericAPIError{Code: "NoSuchEntity"}, false},
+		{"vela server error", isRetryableVelaError, &httpStatusError{StatusCode: 502}, true},
+		{"vela rate limited", isRetryableVelaError, &httpStatusError{StatusCode: 429}, true},
+		{"vela unauthorized", isRetryableVelaError, &httpStatusError{StatusCode: 401}, false},
+		{"vela network error", isRetryableVelaError, &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.retryable(tt.err))
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// RoleRule represents a role to assume when the build matches every condition
+// of the rule. Empty conditions match anything, so a rule without conditions
+// matches every build.
+type RoleRule struct {
+	Name     string `yaml:"name"`
+	Branch   string `yaml:"branch"`
+	Event    string `yaml:"event"`
+	Tag      string `yaml:"tag"`
+	Target   string `yaml:"target"`
+	Role     string `yaml:"role"`
+	Region   string `yaml:"region"`
+	Duration int    `yaml:"duration"`
+}
+
+// ParseRoleRules parses an ordered YAML or JSON list of role rules, rejecting unknown keys.
+func ParseRoleRules(data string) ([]RoleRule, error) {
+	var rules []RoleRule
+
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(&rules)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse role rules: %w", err)
+	}
+
+	for i, rule := range rules {
+		if rule.Role == "" {
+			return nil, fmt.Errorf("role rule %s has no role", rule.name(i))
+		}
+	}
+
+	return rules, nil
+}
+
+// matches reports whether the build matches every condition of the rule.
+func (r *RoleRule) matches(build *BuildContext) bool {
+	match := func(pattern, value string) bool {
+		return pattern == "" || matchWildcard(pattern, value)
+	}
+
+	return match(r.Branch, build.Branch) && match(r.Event, build.Event) && match(r.Tag, build.Tag) && match(r.Target, build.Target)
+}
+
+// name returns the name of the rule for messages, falling back to its index.
+func (r *RoleRule) name(i int) string {
+	if r.Name != "" {
+		return fmt.Sprintf("%q", r.Name)
+	}
+
+	return fmt.Sprintf("#%d", i)
+}
+
+// applyRoleRules selects the role, region and duration from the first role
+// rule matching the build. When no rule matches, the role, region and
+// role_duration_seconds parameters are used as the default.
+func (c *Config) applyRoleRules() error {
+	if c.AWS.RoleRules == "" {
+		return nil
+	}
+
+	rules, err := ParseRoleRules(c.AWS.RoleRules)
+	if err != nil {
+		return err
+	}
+
+	for i, rule := range rules {
+		if !rule.matches(c.Build) {
+			continue
+		}
+
+		c.AWS.Role = rule.Role
+
+		if rule.Region != "" {
+			c.AWS.Region = rule.Region
+		}
+
+		if rule.Duration != 0 {
+			c.AWS.RoleDurationSeconds = rule.Duration
+		}
+
+		c.Logger.Infof("role rule %s matched, using role %s", rule.name(i), c.AWS.Role)
+
+		return nil
+	}
+
+	if c.AWS.Role == "" {
+		return fmt.Errorf("no role rule matches branch %q, event %q, tag %q and target %q, and no default role provided",
+			c.Build.Branch, c.Build.Event, c.Build.Tag, c.Build.Target)
+	}
+
+	c.Logger.Infof("no role rule matched, using default role %s", c.AWS.Role)
+
+	return nil
+}
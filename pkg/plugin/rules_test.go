@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_applyRoleRules(t *testing.T) {
+	rules := `
+- name: production
+  event: deployment
+  target: production
+  role: arn:aws:iam::123456123456:role/deploy-production
+  region: us-west-2
+  duration: 900
+- name: releases
+  event: tag
+  tag: v*
+  role: arn:aws:iam::123456123456:role/release
+- name: main
+  branch: main
+  event: push
+  role: arn:aws:iam::123456123456:role/deploy-staging
+`
+
+	tests := []struct {
+		name         string
+		build        *BuildContext
+		role         string
+		wantRole     string
+		wantRegion   string
+		wantDuration int
+		wantErr      bool
+	}{
+		{
+			name:         "deployment target",
+			build:        &BuildContext{Branch: "main", Event: "deployment", Target: "production"},
+			wantRole:     "arn:aws:iam::123456123456:role/deploy-production",
+			wantRegion:   "us-west-2",
+			wantDuration: 900,
+		},
+		{
+			name:         "tag pattern",
+			build:        &BuildContext{Branch: "main", Event: "tag", Tag: "v1.2.3"},
+			wantRole:     "arn:aws:iam::123456123456:role/release",
+			wantRegion:   "us-east-1",
+			wantDuration: 3600,
+		},
+		{
+			name:         "first matching rule wins",
+			build:        &BuildContext{Branch: "main", Event: "push"},
+			wantRole:     "arn:aws:iam::123456123456:role/deploy-staging",
+			wantRegion:   "us-east-1",
+			wantDuration: 3600,
+		},
+		{
+			name:         "default role",
+			build:        &BuildContext{Branch: "feature", Event: "pull_request"},
+			role:         "arn:aws:iam::123456123456:role/read-only",
+			wantRole:     "arn:aws:iam::123456123456:role/read-only",
+			wantRegion:   "us-east-1",
+			wantDuration: 3600,
+		},
+		{
+			name:    "no match without default role",
+			build:   &BuildContext{Branch: "feature", Event: "pull_request"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{
+				AWS: &AWS{
+					Region:              "us-east-1",
+					Role:                tt.role,
+					RoleDurationSeconds: 3600,
+					RoleRules:           rules,
+				},
+				Build:  tt.build,
+				Logger: logrus.NewEntry(logrus.StandardLogger()),
+			}
+
+			err := c.applyRoleRules()
+			if tt.wantErr {
+				assert.Error(t, err, "An error was expected")
+				return
+			}
+
+			assert.NoError(t, err, "No error was expected")
+			assert.Equal(t, tt.wantRole, c.AWS.Role)
+			assert.Equal(t, tt.wantRegion, c.AWS.Region)
+			assert.Equal(t, tt.wantDuration, c.AWS.RoleDurationSeconds)
+		})
+	}
+}
+
+func TestPlugin_ParseRoleRules_Invalid(t *testing.T) {
+	_, err := ParseRoleRules(`[{"branch": "main", "role": "arn:aws:iam::123456123456:role/test", "rol": "typo"}]`)
+	assert.Error(t, err, "unknown keys should be rejected")
+
+	_, err = ParseRoleRules(`[{"branch": "main"}]`)
+	assert.ErrorContains(t, err, "has no role")
+}
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/urfave/cli/v2"
+)
+
+// schemaDraft represents the JSON Schema dialect of the config file schema.
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// parameterEnums represents the allowed values of the parameters that accept a fixed set.
+var parameterEnums = map[string][]string{
+	FlagAction:         {ActionCredentials, ActionCleanup},
+	FlagKubeconfigAuth: {KubeconfigAuthToken, KubeconfigAuthExec},
+	FlagScriptFormat:   {ScriptFormatShell, ScriptFormatCredentialFile},
+}
+
+// Schema returns the JSON Schema of the config file, describing every
+// parameter with its type, default and usage.
+func Schema() ([]byte, error) {
+	properties := map[string]any{}
+
+	for _, param := range Parameters() {
+		properties[param.Name] = parameterSchema(param)
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"$schema":              schemaDraft,
+		"title":                "vela-aws-credentials config file",
+		"description":          "Parameters of the vela-aws-credentials plugin. Parameters set in the step override the config file.",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}, "", "  ")
+}
+
+// parameterSchema returns the JSON Schema of a single parameter.
+func parameterSchema(param Parameter) map[string]any {
+	name := param.Flag.Names()[0]
+
+	schema := map[string]any{
+		"description": param.Flag.GetUsage(),
+	}
+
+	switch f := param.Flag.(type) {
+	case *cli.BoolFlag:
+		schema["type"] = "boolean"
+		schema["default"] = f.Value
+	case *cli.IntFlag:
+		schema["type"] = "integer"
+		schema["default"] = f.Value
+	case *cli.Float64Flag:
+		schema["type"] = "number"
+		schema["default"] = f.Value
+	case *cli.DurationFlag:
+		schema["type"] = "string"
+		schema["default"] = f.Value.String()
+	case *cli.IntSliceFlag:
+		schema["type"] = []string{"array", "string"}
+		schema["items"] = map[string]any{"type": "integer"}
+
+		if f.Value != nil {
+			schema["default"] = f.Value.Value()
+		}
+	case *cli.StringSliceFlag:
+		schema["type"] = []string{"array", "string"}
+		schema["items"] = map[string]any{"type": "string"}
+
+		if f.Value != nil {
+			schema["default"] = f.Value.Value()
+		}
+	case *cli.StringFlag:
+		schema["type"] = "string"
+
+		if slices.Contains(structuredParameters, name) {
+			schema["type"] = []string{"string", "object", "array"}
+		}
+
+		if f.Value != "" {
+			schema["default"] = f.Value
+		}
+	}
+
+	if enum, ok := parameterEnums[name]; ok {
+		schema["enum"] = enum
+	}
+
+	return schema
+}
@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchema(t *testing.T) {
+	data, err := Schema()
+	assert.NoError(t, err)
+
+	var schema struct {
+		AdditionalProperties bool                      `json:"additionalProperties"`
+		Properties           map[string]map[string]any `json:"properties"`
+	}
+
+	err = json.Unmarshal(data, &schema)
+	assert.NoError(t, err)
+
+	assert.False(t, schema.AdditionalProperties)
+
+	// every parameter is described, so the schema rejects the same keys as the plugin
+	assert.Len(t, schema.Properties, len(Parameters()))
+
+	assert.Equal(t, map[string]any{
+		"type":        "integer",
+		"default":     float64(3600),
+		"description": "Role duration in seconds",
+	}, schema.Properties["role_duration_seconds"])
+
+	assert.Equal(t, []any{"string", "object", "array"}, schema.Properties["role_rules"]["type"])
+	assert.Equal(t, []any{ScriptFormatShell, ScriptFormatCredentialFile}, schema.Properties["script_format"]["enum"])
+	assert.Equal(t, "boolean", schema.Properties["verify"]["type"])
+
+	for _, name := range []string{"allowlist", "config_file", "workspace", "build_number"} {
+		assert.NotContains(t, schema.Properties, name)
+	}
+}
@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"go.yaml.in/yaml/v3"
+)
+
+// envNamePattern represents the names accepted for environment variables written to the secrets env file.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Secret represents a Secrets Manager secret or SSM parameter fetched with the
+// assumed role credentials.
+type Secret struct {
+	Name         string `yaml:"name"`
+	SecretID     string `yaml:"secret_id"`
+	Parameter    string `yaml:"parameter"`
+	Key          string `yaml:"key"`
+	VersionID    string `yaml:"version_id"`
+	VersionStage string `yaml:"version_stage"`
+	Version      string `yaml:"version"`
+	File         string `yaml:"file"`
+}
+
+// ParseSecrets parses a YAML or JSON list of secrets, rejecting unknown keys.
+func ParseSecrets(data string) ([]Secret, error) {
+	var secrets []Secret
+
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(&secrets)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse secrets: %w", err)
+	}
+
+	var errs []error
+
+	names := map[string]bool{}
+
+	for i, secret := range secrets {
+		switch {
+		case !envNamePattern.MatchString(secret.Name):
+			errs = append(errs, fmt.Errorf("secret #%d has an invalid name %q (expected an environment variable name)", i, secret.Name))
+		case names[secret.Name]:
+			errs = append(errs, fmt.Errorf("secret %s is listed more than once", secret.Name))
+		}
+
+		names[secret.Name] = true
+
+		switch {
+		case (secret.SecretID == "") == (secret.Parameter == ""):
+			errs = append(errs, fmt.Errorf("secret %s must have exactly one of secret_id or parameter", secret.Name))
+		case secret.SecretID != "" && secret.Version != "":
+			errs = append(errs, fmt.Errorf("secret %s: version only applies to parameters, use version_id or version_stage", secret.Name))
+		case secret.Parameter != "" && (secret.VersionID != "" || secret.VersionStage != ""):
+			errs = append(errs, fmt.Errorf("secret %s: version_id and version_stage only apply to secret_id, use version", secret.Name))
+		}
+	}
+
+	return secrets, errors.Join(errs...)
+}
+
+// FetchSecrets fetches the secrets with the assumed role credentials and
+// writes them to their files, or to the secrets env file.
+func (c *Config) FetchSecrets(ctx context.Context, creds *aws.Credentials) error {
+	if len(c.secrets) == 0 {
+		return nil
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(credentials.StaticCredentialsProvider{Value: *creds}), config.WithRegion(c.AWS.Region))
+	if err != nil {
+		return err
+	}
+
+	var (
+		env  strings.Builder
+		errs []error
+	)
+
+	for _, secret := range c.secrets {
+		value, err := fetchSecret(ctx, cfg, &secret)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to fetch secret %s: %w", secret.Name, err))
+
+			continue
+		}
+
+		c.redact(value)
+
+		if secret.File == "" {
+			fmt.Fprintf(&env, "export %s=%s\n", secret.Name, shellQuote(value))
+
+			continue
+		}
+
+		err = writeSecretFile(secret.File, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to write secret %s: %w", secret.Name, err))
+
+			continue
+		}
+
+		c.trackFile(secret.File)
+		c.Logger.Infof("wrote secret %s to %s", secret.Name, secret.File)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if env.Len() == 0 {
+		return nil
+	}
+
+	err = writeSecretFile(c.SecretsEnvFile, env.String())
+	if err != nil {
+		return fmt.Errorf("unable to write secrets env file: %w", err)
+	}
+
+	c.trackFile(c.SecretsEnvFile)
+	c.Logger.Infof("wrote secrets env file %s", c.SecretsEnvFile)
+
+	return nil
+}
+
+// fetchSecret returns the value of the secret, extracting its JSON key when
+// configured. A secret or parameter given as an ARN is fetched from the region
+// of the ARN.
+func fetchSecret(ctx context.Context, cfg aws.Config, secret *Secret) (string, error) {
+	var value string
+
+	if region := secretRegion(secret); region != "" {
+		cfg.Region = region
+	}
+
+	if secret.SecretID != "" {
+		input := &secretsmanager.GetSecretValueInput{SecretId: aws.String(secret.SecretID)}
+
+		if secret.VersionID != "" {
+			input.VersionId = aws.String(secret.VersionID)
+		}
+
+		if secret.VersionStage != "" {
+			input.VersionStage = aws.String(secret.VersionStage)
+		}
+
+		output, err := secretsmanager.NewFromConfig(cfg).GetSecretValue(ctx, input)
+		if err != nil {
+			return "", err
+		}
+
+		if output.SecretString == nil {
+			return "", fmt.Errorf("secret %s is binary, only string secrets are supported", secret.SecretID)
+		}
+
+		value = aws.ToString(output.SecretString)
+	} else {
+		name := secret.Parameter
+		if secret.Version != "" {
+			// SSM selects a version or label with a name:selector suffix
+			name += ":" + secret.Version
+		}
+
+		output, err := ssm.NewFromConfig(cfg).GetParameter(ctx, &ssm.GetParameterInput{
+			Name:           aws.String(name),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return "", err
+		}
+
+		value = aws.ToString(output.Parameter.Value)
+	}
+
+	if secret.Key == "" {
+		return value, nil
+	}
+
+	return extractJSONKey(value, secret.Key)
+}
+
+// secretRegion returns the region of the secret or parameter ARN, or an empty
+// string when the secret is not given as an ARN.
+func secretRegion(secret *Secret) string {
+	id := secret.SecretID
+	if id == "" {
+		id = secret.Parameter
+	}
+
+	parsed, err := arn.Parse(id)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.Region
+}
+
+// extractJSONKey returns the value of key from a JSON object. String values are
+// returned as is and other values as JSON.
+func extractJSONKey(value, key string) (string, error) {
+	var object map[string]json.RawMessage
+
+	err := json.Unmarshal([]byte(value), &object)
+	if err != nil {
+		return "", fmt.Errorf("unable to extract key %s, the value is not a JSON object", key)
+	}
+
+	raw, ok := object[key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found", key)
+	}
+
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s, nil
+	}
+
+	return string(raw), nil
+}
+
+// writeSecretFile writes the secret value to path, readable only by its owner.
+func writeSecretFile(path, value string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	// WriteFile keeps the mode of an existing file
+	err = os.WriteFile(path, []byte(value), 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Chmod(path, 0600)
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
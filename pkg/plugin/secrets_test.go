@@ -0,0 +1,218 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "secrets and parameters",
+			data: `
+- name: DB_PASSWORD
+  secret_id: arn:aws:secretsmanager:us-east-1:123456123456:secret:db-AbCdEf
+  key: password
+  version_stage: AWSPREVIOUS
+- name: API_TOKEN
+  parameter: /ci/api-token
+  version: "3"
+  file: /vela/secrets/aws/api-token
+`,
+		},
+		{
+			name:    "invalid name",
+			data:    `[{"name": "db-password", "parameter": "/ci/db"}]`,
+			wantErr: `secret #0 has an invalid name "db-password"`,
+		},
+		{
+			name:    "duplicate name",
+			data:    `[{"name": "DB", "parameter": "/ci/db"}, {"name": "DB", "parameter": "/ci/other"}]`,
+			wantErr: "secret DB is listed more than once",
+		},
+		{
+			name:    "no source",
+			data:    `[{"name": "DB"}]`,
+			wantErr: "secret DB must have exactly one of secret_id or parameter",
+		},
+		{
+			name:    "both sources",
+			data:    `[{"name": "DB", "secret_id": "db", "parameter": "/ci/db"}]`,
+			wantErr: "secret DB must have exactly one of secret_id or parameter",
+		},
+		{
+			name:    "parameter version on a secret",
+			data:    `[{"name": "DB", "secret_id": "db", "version": "3"}]`,
+			wantErr: "version only applies to parameters",
+		},
+		{
+			name:    "secret stage on a parameter",
+			data:    `[{"name": "DB", "parameter": "/ci/db", "version_stage": "AWSCURRENT"}]`,
+			wantErr: "version_id and version_stage only apply to secret_id",
+		},
+		{
+			name:    "unknown key",
+			data:    `[{"name": "DB", "parameter": "/ci/db", "stage": "AWSCURRENT"}]`,
+			wantErr: "field stage not found",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseSecrets(test.data)
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestConfig_FetchSecrets(t *testing.T) {
+	var requests []map[string]any
+
+	newTestAWS(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			t.Errorf("unable to decode request: %v", err)
+		}
+
+		requests = append(requests, body)
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+
+		switch target := r.Header.Get("X-Amz-Target"); {
+		case strings.HasSuffix(target, ".GetSecretValue"):
+			fmt.Fprintf(w, `{"Name": "db", "SecretString": %q}`, `{"username": "app", "password": "it's-secret", "port": 5432}`)
+		case strings.HasSuffix(target, ".GetParameter"):
+			fmt.Fprint(w, `{"Parameter": {"Name": "/ci/api-token", "Value": "api-token-value"}}`)
+		default:
+			t.Errorf("unexpected request %s", target)
+		}
+	})
+
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "secrets.env")
+	tokenFile := filepath.Join(dir, "files", "api-token")
+
+	secrets, err := ParseSecrets(fmt.Sprintf(`
+- name: DB_PASSWORD
+  secret_id: db
+  key: password
+  version_stage: AWSPREVIOUS
+- name: DB_PORT
+  secret_id: db
+  key: port
+- name: API_TOKEN
+  parameter: /ci/api-token
+  version: "3"
+  file: %s
+`, tokenFile))
+	if err != nil {
+		t.Fatalf("unable to parse secrets: %v", err)
+	}
+
+	c := &Config{
+		SecretsEnvFile: envFile,
+		AWS:            &AWS{Region: "us-east-1"},
+		Redactor:       NewRedactor(),
+		Logger:         logrus.NewEntry(logrus.StandardLogger()),
+		secrets:        secrets,
+	}
+
+	err = c.FetchSecrets(context.Background(), &aws.Credentials{AccessKeyID: "ACCESS_KEY_ID", SecretAccessKey: "SECRET_ACCESS_KEY"})
+	assert.NoError(t, err)
+
+	if assert.Len(t, requests, 3) {
+		assert.Equal(t, map[string]any{"SecretId": "db", "VersionStage": "AWSPREVIOUS"}, requests[0])
+		assert.Equal(t, map[string]any{"Name": "/ci/api-token:3", "WithDecryption": true}, requests[2])
+	}
+
+	env, err := os.ReadFile(envFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "export DB_PASSWORD='it'\\''s-secret'\nexport DB_PORT='5432'\n", string(env))
+
+	token, err := os.ReadFile(tokenFile)
+	assert.NoError(t, err)
+	assert.Equal(t, "api-token-value", string(token))
+
+	for _, file := range []string{envFile, tokenFile} {
+		info, err := os.Stat(file)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// the files are recorded for the cleanup action and the values are redacted
+	assert.Equal(t, []string{tokenFile, envFile}, c.writtenFiles)
+	assert.Equal(t, "[REDACTED] [REDACTED]", c.Redactor.Redact("it's-secret api-token-value"))
+}
+
+func TestSecretRegion(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret *Secret
+		want   string
+	}{
+		{"secret name", &Secret{SecretID: "ci/db"}, ""},
+		{"secret arn", &Secret{SecretID: "arn:aws:secretsmanager:eu-west-1:123456123456:secret:ci/db-AbCdEf"}, "eu-west-1"},
+		{"parameter name", &Secret{Parameter: "/ci/npm-token"}, ""},
+		{"parameter arn", &Secret{Parameter: "arn:aws:ssm:us-west-2:123456123456:parameter/ci/npm-token"}, "us-west-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, secretRegion(tt.secret))
+		})
+	}
+}
+
+func TestExtractJSONKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		key     string
+		want    string
+		wantErr string
+	}{
+		{name: "string", value: `{"password": "secret"}`, key: "password", want: "secret"},
+		{name: "number", value: `{"port": 5432}`, key: "port", want: "5432"},
+		{name: "object", value: `{"db": {"host": "localhost"}}`, key: "db", want: `{"host": "localhost"}`},
+		{name: "missing key", value: `{"password": "secret"}`, key: "username", wantErr: "key username not found"},
+		{name: "not an object", value: "secret", key: "password", wantErr: "the value is not a JSON object"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := extractJSONKey(test.value, test.key)
+			if test.wantErr != "" {
+				assert.ErrorContains(t, err, test.wantErr)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
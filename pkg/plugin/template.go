@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// TemplateData represents the build context available to templated parameters,
+// along with the account of the role.
+type TemplateData struct {
+	*BuildContext
+	AccountID string
+}
+
+// templateData returns the build context of the plugin for templated parameters.
+func (c *Config) templateData() *TemplateData {
+	data := &TemplateData{BuildContext: c.Build}
+	if data.BuildContext == nil {
+		data.BuildContext = new(BuildContext)
+	}
+
+	if role, err := ParseRoleARN(c.AWS.Role); err == nil {
+		data.AccountID = role.AccountID
+	}
+
+	return data
+}
+
+// renderTemplate renders text as a Go template with the build context,
+// returning text unchanged when it contains no template actions.
+func (c *Config) renderTemplate(name, text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse %s template: %w", name, err)
+	}
+
+	buf := new(bytes.Buffer)
+
+	err = tmpl.Execute(buf, c.templateData())
+	if err != nil {
+		return "", fmt.Errorf("unable to render %s template: %w", name, err)
+	}
+
+	return buf.String(), nil
+}
+
+// renderDocumentTemplate renders the template actions of each key and value
+// of a JSON or YAML document with the build context, returning the document as
+// YAML. Rendering scalars rather than the raw text keeps values such as branch
+// names from changing the structure of the document.
+func (c *Config) renderDocumentTemplate(name, text string) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	var node yaml.Node
+
+	err := yaml.Unmarshal([]byte(text), &node)
+	if err != nil {
+		return "", fmt.Errorf("%s is not valid JSON or YAML, quote values starting with a template action: %w", name, err)
+	}
+
+	err = c.renderScalars(name, &node)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := yaml.Marshal(&node)
+	if err != nil {
+		return "", fmt.Errorf("unable to render %s template: %w", name, err)
+	}
+
+	return string(data), nil
+}
+
+// renderScalars renders the template actions of the scalars under node as strings.
+func (c *Config) renderScalars(name string, node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "{{") {
+		value, err := c.renderTemplate(name, node.Value)
+		if err != nil {
+			return err
+		}
+
+		node.Value = value
+		node.Tag = "!!str"
+		node.Style = yaml.DoubleQuotedStyle
+
+		return nil
+	}
+
+	for _, child := range node.Content {
+		err := c.renderScalars(name, child)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
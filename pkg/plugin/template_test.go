@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_renderTemplate(t *testing.T) {
+	c := &Config{
+		AWS: &AWS{
+			Role: "arn:aws:iam::123456123456:role/shared",
+		},
+		Build: &BuildContext{
+			Org:         "octo-org",
+			Repo:        "octo-repo",
+			Branch:      "main",
+			BuildNumber: 42,
+			Commit:      "0123456789abcdef",
+			Event:       "push",
+		},
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "no template actions",
+			text: "arn:aws:s3:::artifacts/*",
+			want: "arn:aws:s3:::artifacts/*",
+		},
+		{
+			name: "build context",
+			text: "arn:aws:s3:::artifacts-{{ .AccountID }}/{{ .Org }}/{{ .Repo }}/{{ .Branch }}/{{ .BuildNumber }}/*",
+			want: "arn:aws:s3:::artifacts-123456123456/octo-org/octo-repo/main/42/*",
+		},
+		{
+			name: "extended build context",
+			text: "{{ .Event }}-{{ .Commit }}",
+			want: "push-0123456789abcdef",
+		},
+		{
+			name:    "unknown field",
+			text:    "{{ .Unknown }}",
+			wantErr: true,
+		},
+		{
+			name:    "invalid template",
+			text:    "{{ .Org ",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.renderTemplate("test", tt.text)
+			if tt.wantErr {
+				assert.Error(t, err, "An error was expected")
+				return
+			}
+
+			assert.NoError(t, err, "No error was expected")
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlugin_Validate_TemplatedInlineSessionPolicy(t *testing.T) {
+	c := &Config{
+		AWS: &AWS{
+			Region:              "us-east-1",
+			Role:                "arn:aws:iam::123456123456:role/shared",
+			RoleDurationSeconds: 3600,
+			InlineSessionPolicy: `
+Version: "2012-10-17"
+Statement:
+  - Effect: Allow
+    Action: s3:*
+    Resource: "arn:aws:s3:::artifacts/{{ .Org }}/{{ .Repo }}/*"
+`,
+		},
+		Build: &BuildContext{
+			Org:  "octo-org",
+			Repo: "octo-repo",
+		},
+		//nolint:gosec // ignore false positive for hardcoded credential
+		Vela: &Vela{
+			RequestToken:    "testToken",
+			RequestTokenURL: "http://127.0.0.1",
+		},
+		Logger:       logrus.NewEntry(logrus.StandardLogger()),
+		ScriptFormat: ScriptFormatShell,
+	}
+
+	err := c.Validate()
+	assert.NoError(t, err, "No error was expected")
+	assert.Equal(t,
+		`{"Statement":[{"Action":"s3:*","Effect":"Allow","Resource":"arn:aws:s3:::artifacts/octo-org/octo-repo/*"}],"Version":"2012-10-17"}`,
+		c.inlineSessionPolicy)
+}
+
+func TestPlugin_Validate_TemplatedInlineSessionPolicy_Injection(t *testing.T) {
+	policies := map[string]string{
+		"json": `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "s3:*", "Resource": ["arn:aws:s3:::artifacts/{{ .Branch }}/*"]}]}`,
+		"yaml": `
+Version: "2012-10-17"
+Statement:
+  - Effect: Allow
+    Action: s3:*
+    Resource:
+      - arn:aws:s3:::artifacts/{{ .Branch }}/*
+`,
+	}
+
+	for name, policy := range policies {
+		t.Run(name, func(t *testing.T) {
+			c := &Config{
+				AWS: &AWS{
+					Region:              "us-east-1",
+					Role:                "arn:aws:iam::123456123456:role/shared",
+					RoleDurationSeconds: 3600,
+					InlineSessionPolicy: policy,
+				},
+				Build: &BuildContext{
+					Branch: `b/x","arn:aws:s3:::prod-bucket/`,
+				},
+				//nolint:gosec // ignore false positive for hardcoded credential
+				Vela: &Vela{
+					RequestToken:    "testToken",
+					RequestTokenURL: "http://127.0.0.1",
+				},
+				Logger:       logrus.NewEntry(logrus.StandardLogger()),
+				ScriptFormat: ScriptFormatShell,
+			}
+
+			err := c.Validate()
+			assert.NoError(t, err, "No error was expected")
+
+			// the branch stays inside the single resource instead of adding one
+			assert.Equal(t,
+				`{"Statement":[{"Action":"s3:*","Effect":"Allow","Resource":["arn:aws:s3:::artifacts/b/x\",\"arn:aws:s3:::prod-bucket//*"]}],"Version":"2012-10-17"}`,
+				c.inlineSessionPolicy)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+data "tls_certificate" "vela" {
+  url = "https://vela-server.com/_services/token"
+}
+
+resource "aws_iam_openid_connect_provider" "vela" {
+  url             = "https://vela-server.com/_services/token"
+  client_id_list  = ["sts.amazonaws.com"]
+  thumbprint_list = [data.tls_certificate.vela.certificates[0].sha1_fingerprint]
+}
+
+data "aws_iam_policy_document" "deploy_role_assume_role" {
+  statement {
+    actions = ["sts:AssumeRoleWithWebIdentity"]
+
+    principals {
+      type        = "Federated"
+      identifiers = [aws_iam_openid_connect_provider.vela.arn]
+    }
+
+    condition {
+      test     = "StringLike"
+      variable = "vela-server.com/_services/token:sub"
+      values   = [
+        "repo:octo-org/octo-repo:ref:refs/heads/main:event:push",
+        "repo:octo-org/octo-repo:ref:refs/heads/release:event:push",
+      ]
+    }
+
+    condition {
+      test     = "StringEquals"
+      variable = "vela-server.com/_services/token:aud"
+      values   = ["sts.amazonaws.com"]
+    }
+  }
+}
+
+resource "aws_iam_role" "deploy_role" {
+  name               = "deploy-role"
+  assume_role_policy = data.aws_iam_policy_document.deploy_role_assume_role.json
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Phases of the plugin execution used in timeout and cancellation errors.
+const (
+	phaseToken        = "ID token request"
+	phaseAssumeRole   = "role assumption"
+	phaseVerify       = "credential verification"
+	phaseSecrets      = "secret retrieval"
+	phaseECR          = "ECR login"
+	phaseCodeArtifact = "CodeArtifact login"
+	phaseKubeconfig   = "kubeconfig generation"
+	phaseRevoke       = "session revocation"
+)
+
+// runPhase calls fn with a context bounded by the phase timeout and reports
+// which phase timed out or was canceled when fn fails because of its context.
+// A timeout of zero leaves the phase bounded only by the parent context.
+func (c *Config) runPhase(ctx context.Context, phase string, timeout time.Duration, fn func(context.Context) error) error {
+	phaseCtx := ctx
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		phaseCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := fn(phaseCtx)
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("plugin timed out after %s during %s: %w", c.Timeout.Overall, phase, err)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("%s canceled: %w", phase, err)
+	case errors.Is(phaseCtx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%s timed out after %s: %w", phase, timeout, err)
+	}
+
+	return err
+}
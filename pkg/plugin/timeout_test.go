@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_runPhase(t *testing.T) {
+	c := &Config{Timeout: &Timeout{Overall: time.Millisecond}}
+
+	wait := func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}
+
+	t.Run("phase timeout", func(t *testing.T) {
+		err := c.runPhase(context.Background(), phaseAssumeRole, time.Millisecond, wait)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "role assumption timed out after 1ms")
+	})
+
+	t.Run("overall timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+
+		err := c.runPhase(ctx, phaseToken, time.Minute, wait)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "plugin timed out after 1ms during ID token request")
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := c.runPhase(ctx, phaseVerify, 0, wait)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorContains(t, err, "credential verification canceled")
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		errTest := errors.New("test")
+
+		err := c.runPhase(context.Background(), phaseVerify, time.Minute, func(context.Context) error { return errTest })
+		assert.Equal(t, errTest, err)
+	})
+}
+
+func TestConfig_deliverCredentials_PhaseTimeout(t *testing.T) {
+	newTestAWS(t, func(_ http.ResponseWriter, r *http.Request) {
+		// outlast the phase timeout
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+
+	dir := t.TempDir()
+
+	c := &Config{
+		ManifestPath:  filepath.Join(dir, "manifest.json"),
+		DockerConfig:  filepath.Join(dir, "docker", "config.json"),
+		AWS:           &AWS{Region: "us-east-1"},
+		Timeout:       &Timeout{ECR: 50 * time.Millisecond},
+		Redactor:      NewRedactor(),
+		Logger:        logrus.NewEntry(logrus.StandardLogger()),
+		ecrRegistries: []ECRRegistry{{Host: "123456123456.dkr.ecr.us-east-1.amazonaws.com", Region: "us-east-1"}},
+	}
+
+	err := c.deliverCredentials(context.Background(), &aws.Credentials{AccessKeyID: "ACCESS_KEY_ID", SecretAccessKey: "SECRET_ACCESS_KEY"})
+	assert.ErrorContains(t, err, "ECR login timed out after 50ms")
+	assert.Equal(t, ErrorKindLogin.ExitCode(), ExitCode(err))
+}
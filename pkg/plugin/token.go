@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type (
+	// openIDConfig represents the subset of the OIDC discovery document used by the plugin.
+	openIDConfig struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+
+	// jwk represents a single JSON Web Key from the issuer's key set.
+	jwk struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		E   string `json:"e"`
+		N   string `json:"n"`
+	}
+
+	// jwks represents the JSON Web Key Set published by the issuer.
+	jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+)
+
+// TokenIssuer returns the expected issuer of the ID token. When no issuer
+// is configured, it is derived from the Vela request token URL.
+func (c *Config) TokenIssuer() (string, error) {
+	if c.Issuer != "" {
+		return strings.TrimSuffix(c.Issuer, "/"), nil
+	}
+
+	tokenURL, err := url.Parse(c.Vela.RequestTokenURL)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("https://%s/_services/token", tokenURL.Hostname()), nil
+}
+
+// VerifyIDToken verifies the signature, issuer and audience of the ID token
+// against the keys published by the issuer and returns the token claims.
+func (c *Config) VerifyIDToken(ctx context.Context, token string) (jwt.MapClaims, error) {
+	issuer, err := c.TokenIssuer()
+	if err != nil {
+		return nil, err
+	}
+
+	jwksURL := c.JWKSURL
+	if jwksURL == "" {
+		discovery := new(openIDConfig)
+
+		err = getJSON(ctx, fmt.Sprintf("%s/.well-known/openid-configuration", issuer), discovery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch OIDC discovery document: %w", err)
+		}
+
+		if discovery.Issuer != issuer {
+			return nil, fmt.Errorf("OIDC discovery document issuer %q does not match expected issuer %q", discovery.Issuer, issuer)
+		}
+
+		jwksURL = discovery.JWKSURI
+	}
+
+	keySet := new(jwks)
+
+	err = getJSON(ctx, jwksURL, keySet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	claims := jwt.MapClaims{}
+
+	_, err = jwt.ParseWithClaims(token, claims, keySet.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(c.Audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify ID token: %w", err)
+	}
+
+	c.Logger.Infof("successfully verified ID token signature for issuer %s", issuer)
+
+	return claims, nil
+}
+
+// DecodeClaims returns the claims of the ID token without verifying its signature.
+func DecodeClaims(token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+
+	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ID token: %w", err)
+	}
+
+	return claims, nil
+}
+
+// keyFunc returns the RSA public key matching the kid header of the token.
+func (k *jwks) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	for _, key := range k.Keys {
+		if key.Kid != kid {
+			continue
+		}
+
+		if key.Kty != "RSA" {
+			return nil, fmt.Errorf("unsupported key type %q for kid %q", key.Kty, kid)
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus for kid %q: %w", kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent for kid %q: %w", kid, err)
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no key found in JWKS for kid %q", kid)
+}
+
+// getJSON fetches the provided URL and decodes the JSON response into v.
+func getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, u)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestIssuer starts a local OIDC issuer that publishes the public half of key.
+func newTestIssuer(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	issuer := srv.URL + "/_services/token"
+
+	mux.HandleFunc("/_services/token/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(openIDConfig{Issuer: issuer, JWKSURI: issuer + "/.well-known/jwks"})
+	})
+
+	mux.HandleFunc("/_services/token/.well-known/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(jwks{Keys: []jwk{{
+			Kty: "RSA",
+			Kid: "test-kid",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	return srv
+}
+
+// signTestToken returns an RS256 ID token signed by key.
+func signTestToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	tk := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tk.Header["kid"] = kid
+
+	token, err := tk.SignedString(key)
+	if err != nil {
+		t.Fatalf("unable to sign token: %v", err)
+	}
+
+	return token
+}
+
+func TestConfig_VerifyIDToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	srv := newTestIssuer(t, key)
+	issuer := srv.URL + "/_services/token"
+
+	claims := func(iss, aud string, exp time.Time) jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": iss,
+			"aud": aud,
+			"sub": "repo:octo-org/octo-repo:ref:refs/heads/main:event:push",
+			"exp": exp.Unix(),
+			"iat": time.Now().Unix(),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		issuer  string
+		jwksURL string
+		token   string
+		wantErr bool
+	}{
+		{
+			name:   "valid token",
+			issuer: issuer,
+			token:  signTestToken(t, key, "test-kid", claims(issuer, "sts.amazonaws.com", time.Now().Add(time.Hour))),
+		},
+		{
+			name:    "valid token with configured JWKS URL",
+			issuer:  issuer,
+			jwksURL: issuer + "/.well-known/jwks",
+			token:   signTestToken(t, key, "test-kid", claims(issuer, "sts.amazonaws.com", time.Now().Add(time.Hour))),
+		},
+		{
+			name:    "signed by unknown key",
+			issuer:  issuer,
+			token:   signTestToken(t, otherKey, "test-kid", claims(issuer, "sts.amazonaws.com", time.Now().Add(time.Hour))),
+			wantErr: true,
+		},
+		{
+			name:    "unknown kid",
+			issuer:  issuer,
+			token:   signTestToken(t, key, "other-kid", claims(issuer, "sts.amazonaws.com", time.Now().Add(time.Hour))),
+			wantErr: true,
+		},
+		{
+			name:    "issuer mismatch",
+			issuer:  issuer,
+			token:   signTestToken(t, key, "test-kid", claims("https://evil.example.com", "sts.amazonaws.com", time.Now().Add(time.Hour))),
+			wantErr: true,
+		},
+		{
+			name:    "audience mismatch",
+			issuer:  issuer,
+			token:   signTestToken(t, key, "test-kid", claims(issuer, "other", time.Now().Add(time.Hour))),
+			wantErr: true,
+		},
+		{
+			name:    "expired token",
+			issuer:  issuer,
+			token:   signTestToken(t, key, "test-kid", claims(issuer, "sts.amazonaws.com", time.Now().Add(-time.Hour))),
+			wantErr: true,
+		},
+		{
+			name:    "discovery document issuer mismatch",
+			issuer:  srv.URL,
+			token:   signTestToken(t, key, "test-kid", claims(srv.URL, "sts.amazonaws.com", time.Now().Add(time.Hour))),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{
+				Audience: "sts.amazonaws.com",
+				Issuer:   tt.issuer,
+				JWKSURL:  tt.jwksURL,
+				Logger:   logrus.NewEntry(logrus.StandardLogger()),
+			}
+
+			got, err := c.VerifyIDToken(context.Background(), tt.token)
+			if tt.wantErr {
+				assert.Error(t, err, "An error was expected")
+				return
+			}
+
+			assert.NoError(t, err, "No error was expected")
+			assert.Equal(t, "repo:octo-org/octo-repo:ref:refs/heads/main:event:push", got["sub"])
+		})
+	}
+}
+
+func TestConfig_TokenIssuer(t *testing.T) {
+	c := &Config{Vela: &Vela{RequestTokenURL: "https://vela.example.com/api/v1/repos/org/repo/builds/1/id_request_token"}}
+
+	got, err := c.TokenIssuer()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://vela.example.com/_services/token", got)
+
+	c.Issuer = "https://issuer.example.com/"
+
+	got, err = c.TokenIssuer()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://issuer.example.com", got)
+}
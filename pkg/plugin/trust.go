@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// resourceNamePattern matches characters that are not allowed in Terraform resource names.
+var resourceNamePattern = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// accountIDPlaceholder represents the account ID in a trust policy generated without one.
+const accountIDPlaceholder = "<ACCOUNT_ID>"
+
+// TrustPolicyInput represents the information used to generate an IAM trust
+// policy and OIDC provider for a Vela repository.
+type TrustPolicyInput struct {
+	ServerURL string
+	Audience  string
+	AccountID string
+	Partition string
+	RoleName  string
+	Org       string
+	Repo      string
+	Branches  []string
+	Events    []string
+}
+
+// Issuer returns the OIDC issuer URL of the Vela server.
+func (in *TrustPolicyInput) Issuer() string {
+	return strings.TrimSuffix(in.ServerURL, "/") + "/_services/token"
+}
+
+// Provider returns the issuer without its scheme, as used in IAM condition keys and provider ARNs.
+func (in *TrustPolicyInput) Provider() string {
+	issuer := in.Issuer()
+	issuer = strings.TrimPrefix(issuer, "https://")
+
+	return strings.TrimPrefix(issuer, "http://")
+}
+
+// Subjects returns the sub claim patterns allowed to assume the role.
+func (in *TrustPolicyInput) Subjects() []string {
+	repo := in.Repo
+	if repo == "" {
+		repo = "*"
+	}
+
+	refs := []string{"*"}
+	if len(in.Branches) > 0 {
+		refs = nil
+
+		for _, branch := range in.Branches {
+			refs = append(refs, "refs/heads/"+branch)
+		}
+	}
+
+	events := []string{"*"}
+	if len(in.Events) > 0 {
+		events = in.Events
+	}
+
+	var subjects []string
+
+	for _, ref := range refs {
+		for _, event := range events {
+			subjects = append(subjects, fmt.Sprintf("repo:%s/%s:ref:%s:event:%s", in.Org, repo, ref, event))
+		}
+	}
+
+	return subjects
+}
+
+// TrustPolicy returns the IAM trust policy for the role.
+func (in *TrustPolicyInput) TrustPolicy() *PolicyDocument {
+	return &PolicyDocument{
+		Version: "2012-10-17",
+		Statement: PolicyStatements{
+			{
+				Effect: "Allow",
+				Principal: Principal{
+					"Federated": StringList{fmt.Sprintf("arn:%s:iam::%s:oidc-provider/%s", in.partition(), in.accountID(), in.Provider())},
+				},
+				Action: StringList{actionAssumeRoleWithWebIdentity},
+				Condition: map[string]map[string]StringList{
+					"StringLike": {
+						in.Provider() + ":sub": in.Subjects(),
+					},
+					"StringEquals": {
+						in.Provider() + ":aud": {in.Audience},
+					},
+				},
+			},
+		},
+	}
+}
+
+// partition returns the AWS partition of the provider ARN, defaulting to aws.
+func (in *TrustPolicyInput) partition() string {
+	if in.Partition == "" {
+		return "aws"
+	}
+
+	return in.Partition
+}
+
+// accountID returns the account ID of the provider ARN, or a placeholder to
+// replace when no account ID was provided.
+func (in *TrustPolicyInput) accountID() string {
+	if in.AccountID == "" {
+		return accountIDPlaceholder
+	}
+
+	return in.AccountID
+}
+
+// JSON returns the IAM trust policy for the role as indented JSON. Without an
+// account ID, the provider ARN holds the <ACCOUNT_ID> placeholder.
+func (in *TrustPolicyInput) JSON() (string, error) {
+	if _, ok := partitionRegions[in.partition()]; !ok {
+		return "", fmt.Errorf("unknown partition %q", in.partition())
+	}
+
+	buf := new(bytes.Buffer)
+
+	// the placeholder must not be escaped to \u003cACCOUNT_ID\u003e
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+
+	err := enc.Encode(in.TrustPolicy())
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Terraform returns Terraform configuration for the OIDC provider and role.
+func (in *TrustPolicyInput) Terraform() (string, error) {
+	return in.render(terraformTemplate)
+}
+
+// CloudFormation returns a CloudFormation template for the OIDC provider and role.
+func (in *TrustPolicyInput) CloudFormation() (string, error) {
+	return in.render(cloudFormationTemplate)
+}
+
+// ResourceName returns the role name sanitized for use as a Terraform resource name.
+func (in *TrustPolicyInput) ResourceName() string {
+	return resourceNamePattern.ReplaceAllString(in.RoleName, "_")
+}
+
+// render executes the provided template with the input.
+func (in *TrustPolicyInput) render(text string) (string, error) {
+	tmpl, err := template.New("trust").Funcs(template.FuncMap{"quote": strconv.Quote}).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+
+	err = tmpl.Execute(buf, in)
+	if err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+const terraformTemplate = `data "tls_certificate" "vela" {
+  url = {{ quote .Issuer }}
+}
+
+resource "aws_iam_openid_connect_provider" "vela" {
+  url             = {{ quote .Issuer }}
+  client_id_list  = [{{ quote .Audience }}]
+  thumbprint_list = [data.tls_certificate.vela.certificates[0].sha1_fingerprint]
+}
+
+data "aws_iam_policy_document" "{{ .ResourceName }}_assume_role" {
+  statement {
+    actions = ["sts:AssumeRoleWithWebIdentity"]
+
+    principals {
+      type        = "Federated"
+      identifiers = [aws_iam_openid_connect_provider.vela.arn]
+    }
+
+    condition {
+      test     = "StringLike"
+      variable = {{ quote (printf "%s:sub" .Provider) }}
+      values   = [{{ range .Subjects }}
+        {{ quote . }},{{ end }}
+      ]
+    }
+
+    condition {
+      test     = "StringEquals"
+      variable = {{ quote (printf "%s:aud" .Provider) }}
+      values   = [{{ quote .Audience }}]
+    }
+  }
+}
+
+resource "aws_iam_role" "{{ .ResourceName }}" {
+  name               = {{ quote .RoleName }}
+  assume_role_policy = data.aws_iam_policy_document.{{ .ResourceName }}_assume_role.json
+}
+`
+
+const cloudFormationTemplate = `AWSTemplateFormatVersion: "2010-09-09"
+Description: Vela OIDC provider and IAM role for {{ .Org }}/{{ if .Repo }}{{ .Repo }}{{ else }}*{{ end }}
+Resources:
+  VelaOIDCProvider:
+    Type: AWS::IAM::OIDCProvider
+    Properties:
+      Url: {{ quote .Issuer }}
+      ClientIdList:
+        - {{ quote .Audience }}
+  VelaRole:
+    Type: AWS::IAM::Role
+    Properties:
+      RoleName: {{ quote .RoleName }}
+      AssumeRolePolicyDocument:
+        Version: "2012-10-17"
+        Statement:
+          - Effect: Allow
+            Principal:
+              Federated: !Ref VelaOIDCProvider
+            Action: sts:AssumeRoleWithWebIdentity
+            Condition:
+              StringLike:
+                {{ quote (printf "%s:sub" .Provider) }}:{{ range .Subjects }}
+                  - {{ quote . }}{{ end }}
+              StringEquals:
+                {{ quote (printf "%s:aud" .Provider) }}: {{ quote .Audience }}
+Outputs:
+  RoleArn:
+    Value: !GetAtt VelaRole.Arn
+`
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlugin_TrustPolicyInput(t *testing.T) {
+	in := &TrustPolicyInput{
+		ServerURL: "https://vela-server.com/",
+		Audience:  "sts.amazonaws.com",
+		AccountID: "123456123456",
+		RoleName:  "deploy-role",
+		Org:       "octo-org",
+		Repo:      "octo-repo",
+		Branches:  []string{"main", "release"},
+		Events:    []string{"push"},
+	}
+
+	tests := []struct {
+		name     string
+		generate func() (string, error)
+		want     string
+	}{
+		{
+			name:     "json",
+			generate: in.JSON,
+			want:     "testdata/trust_policy.json",
+		},
+		{
+			name:     "terraform",
+			generate: in.Terraform,
+			want:     "testdata/trust_policy.tf",
+		},
+		{
+			name:     "cloudformation",
+			generate: in.CloudFormation,
+			want:     "testdata/trust_policy.cfn.yaml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.generate()
+			assert.NoError(t, err)
+
+			expected, err := os.ReadFile(tt.want)
+			assert.NoError(t, err)
+
+			if diff := cmp.Diff(string(expected), got); diff != "" {
+				t.Errorf("%s mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestPlugin_TrustPolicyInput_Subjects(t *testing.T) {
+	in := &TrustPolicyInput{ServerURL: "https://vela-server.com", Org: "octo-org"}
+
+	assert.Equal(t, []string{"repo:octo-org/*:ref:*:event:*"}, in.Subjects())
+
+	got, err := in.JSON()
+	assert.NoError(t, err)
+	assert.Contains(t, got, `"arn:aws:iam::<ACCOUNT_ID>:oidc-provider/vela-server.com/_services/token"`, "a missing account ID should be a placeholder")
+
+	in.Partition = "aws-cn"
+
+	got, err = in.JSON()
+	assert.NoError(t, err)
+	assert.Contains(t, got, `"arn:aws-cn:iam::<ACCOUNT_ID>:`)
+
+	in.Partition = "aws-moon"
+
+	_, err = in.JSON()
+	assert.ErrorContains(t, err, `unknown partition "aws-moon"`)
+}
@@ -1,7 +1,9 @@
 package plugin
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"slices"
 )
 
@@ -9,24 +11,134 @@ import (
 func (c *Config) Validate() error {
 	c.Logger.Debug("validating plugin configuration")
 
-	// validate that a webhook was supplied
+	c.redact(c.Vela.RequestToken)
+
+	return newError(ErrorKindConfig, c.validate())
+}
+
+// validate checks the plugin configuration, applies defaults and
+// returns every problem found rather than only the first.
+func (c *Config) validate() error {
+	switch c.Action {
+	case ActionCleanup:
+		return c.validateCleanup()
+	case "", ActionCredentials:
+	default:
+		return fmt.Errorf("only actions of %s are supported", []string{ActionCredentials, ActionCleanup})
+	}
+
+	err := errors.Join(
+		c.validateProfile(),
+		c.validateSession(),
+		c.validateRetry(),
+		c.validateScript(),
+		c.validateVerify(),
+		c.validateSecrets(),
+		c.validateECR(),
+		c.validateCodeArtifact(),
+		c.validateEKS(),
+		c.validateOutputs(),
+		c.validateRequestToken(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return c.validateAllowlist()
+}
+
+// validateProfile applies the profile and role rules, then checks the role
+// they resolve to and its region.
+func (c *Config) validateProfile() error {
+	var errs []error
+
+	if err := c.applyProfile(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.applyRoleRules(); err != nil {
+		errs = append(errs, err)
+	}
+
 	if len(c.AWS.Role) == 0 {
-		return fmt.Errorf("no role provided")
+		// role rules and profiles already report a missing role
+		if c.AWS.RoleRules == "" && c.AWS.Profile == "" {
+			errs = append(errs, fmt.Errorf("no role provided"))
+		}
+
+		return errors.Join(errs...)
+	}
+
+	role, err := ParseRoleARN(c.AWS.Role)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+
+	err = validateRegion(c.AWS.Region, role.Partition, c.AWS.AdditionalRegions)
+	if err != nil {
+		errs = append(errs, err)
 	}
 
+	return errors.Join(errs...)
+}
+
+// validateSession checks the duration, name and policies of the role session.
+func (c *Config) validateSession() error {
+	var errs []error
+
 	if c.AWS.RoleDurationSeconds == 0 {
-		return fmt.Errorf("no role duration provided")
+		errs = append(errs, fmt.Errorf("no role duration provided"))
+	} else if err := validateDuration("role duration", c.AWS.RoleDurationSeconds); err != nil {
+		errs = append(errs, err)
 	}
 
-	if c.Vela.RequestTokenURL == "" {
-		return fmt.Errorf("no request token url provided")
+	if c.AWS.DurationFallback {
+		for _, duration := range c.AWS.DurationLadder {
+			if err := validateDuration("duration ladder value", duration); err != nil {
+				errs = append(errs, err)
+			}
+		}
 	}
 
-	supportedFormats := []string{ScriptFormatShell, ScriptFormatCredentialFile}
-	if !slices.Contains(supportedFormats, c.ScriptFormat) {
-		return fmt.Errorf("only script formats of %s are supported", supportedFormats)
+	if err := c.loadRoleSessionName(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.loadInlineSessionPolicy(); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, policy := range c.AWS.ManagedSessionPolicies {
+		if err := validatePolicyARN(policy); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateRetry checks the retry policy.
+func (c *Config) validateRetry() error {
+	if c.Retry == nil {
+		return nil
+	}
+
+	var errs []error
+
+	if c.Retry.Attempts < 1 {
+		errs = append(errs, fmt.Errorf("retry attempts must be at least 1"))
 	}
 
+	if c.Retry.Jitter < 0 || c.Retry.Jitter > 1 {
+		errs = append(errs, fmt.Errorf("retry jitter must be between 0 and 1"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateScript checks the script format and defaults its path to the one
+// of the format.
+func (c *Config) validateScript() error {
 	if c.ScriptPath == "" {
 		switch c.ScriptFormat {
 		case ScriptFormatShell:
@@ -36,9 +148,251 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	supportedFormats := []string{ScriptFormatShell, ScriptFormatCredentialFile}
+	if !slices.Contains(supportedFormats, c.ScriptFormat) {
+		return fmt.Errorf("only script formats of %s are supported", supportedFormats)
+	}
+
+	return nil
+}
+
+// validateVerify parses the verify probes and checks the verify parameters
+// are only set with verify enabled.
+func (c *Config) validateVerify() error {
+	var errs []error
+
+	if c.VerifyProbes != "" {
+		probes, err := ParseVerifyProbes(c.VerifyProbes)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		c.verifyProbes = probes
+	}
+
+	if !c.Verify && (c.VerifyAccountID != "" || c.VerifyAssumedRoleARN != "" || c.VerifyProbes != "") {
+		errs = append(errs, fmt.Errorf("verify_account_id, verify_assumed_role_arn and verify_probes require verify to be enabled"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateSecrets parses the secrets to fetch.
+func (c *Config) validateSecrets() error {
+	if c.Secrets == "" {
+		return nil
+	}
+
+	var errs []error
+
+	secrets, err := ParseSecrets(c.Secrets)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	c.secrets = secrets
+
+	if c.SecretsEnvFile == "" && slices.ContainsFunc(secrets, func(s Secret) bool { return s.File == "" }) {
+		errs = append(errs, fmt.Errorf("no secrets env file provided for the secrets without a file"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateECR parses the ECR registries to log in to.
+func (c *Config) validateECR() error {
+	var errs []error
+
+	c.ecrRegistries = nil
+
+	for _, registry := range c.ECRRegistries {
+		parsed, err := ParseECRRegistry(registry, c.AWS.Region)
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		c.ecrRegistries = append(c.ecrRegistries, *parsed)
+	}
+
+	if len(c.ECRRegistries) > 0 && c.DockerConfig == "" {
+		errs = append(errs, fmt.Errorf("no Docker config file provided for the ECR registries"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateCodeArtifact parses the CodeArtifact domain and repositories.
+func (c *Config) validateCodeArtifact() error {
+	if c.CodeArtifact == "" {
+		return nil
+	}
+
+	var errs []error
+
+	codeArtifact, err := ParseCodeArtifact(c.CodeArtifact)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	c.codeArtifact = codeArtifact
+
+	if c.CodeArtifactDir == "" {
+		errs = append(errs, fmt.Errorf("no CodeArtifact directory provided"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateEKS parses the EKS clusters and checks how the kubeconfig
+// authenticates to them.
+func (c *Config) validateEKS() error {
+	if c.EKSClusters == "" {
+		return nil
+	}
+
+	var errs []error
+
+	clusters, err := ParseEKSClusters(c.EKSClusters, c.AWS.Region)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	c.eksClusters = clusters
+
+	if c.Kubeconfig == "" {
+		errs = append(errs, fmt.Errorf("no kubeconfig provided for the EKS clusters"))
+	}
+
+	supportedAuths := []string{KubeconfigAuthToken, KubeconfigAuthExec}
+	if !slices.Contains(supportedAuths, c.KubeconfigAuth) {
+		errs = append(errs, fmt.Errorf("only kubeconfig auths of %s are supported", supportedAuths))
+	}
+
+	if c.KubeconfigAuth == KubeconfigAuthExec && c.KubeExecCommand == "" {
+		errs = append(errs, fmt.Errorf("no kubeconfig exec command provided"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateOutputs checks the outputs files are known when outputs are written.
+func (c *Config) validateOutputs() error {
+	if c.Outputs == nil {
+		return nil
+	}
+
+	var errs []error
+
+	if c.Outputs.Write && c.Outputs.Path == "" {
+		errs = append(errs, fmt.Errorf("no outputs file provided - VELA_OUTPUTS is not set"))
+	}
+
+	if c.Outputs.WriteMasked && c.Outputs.MaskedPath == "" {
+		errs = append(errs, fmt.Errorf("no masked outputs file provided - VELA_MASKED_OUTPUTS is not set"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateRequestToken checks the ID token can be requested. A dry run only
+// needs it when it is asked to decode it.
+func (c *Config) validateRequestToken() error {
+	if c.DryRun && !c.DryRunToken {
+		return nil
+	}
+
+	var errs []error
+
+	if c.Vela.RequestTokenURL == "" {
+		errs = append(errs, fmt.Errorf("no request token url provided"))
+	}
+
 	if c.Vela.RequestToken == "" {
-		return fmt.Errorf("no request token provided - make sure you have set `id_request: yes` in the step")
+		errs = append(errs, fmt.Errorf("no request token provided - make sure you have set `id_request: yes` in the step"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateCleanup checks the configuration of the cleanup action, which only
+// needs the ID token when sessions are revoked.
+func (c *Config) validateCleanup() error {
+	var errs []error
+
+	if c.ManifestPath == "" {
+		errs = append(errs, fmt.Errorf("no manifest path provided"))
+	}
+
+	if c.AWS.RevokeRole == "" {
+		return errors.Join(errs...)
+	}
+
+	if _, err := ParseRoleARN(c.AWS.RevokeRole); err != nil {
+		errs = append(errs, err)
 	}
 
+	// only the sessions of the role of the step are revoked, so the role is
+	// resolved like for the credentials action
+	if err := c.validateProfile(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.validateAllowlist(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := c.loadRoleSessionName(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.Vela.RequestTokenURL == "" {
+		errs = append(errs, fmt.Errorf("no request token url provided"))
+	}
+
+	if c.Vela.RequestToken == "" {
+		errs = append(errs, fmt.Errorf("no request token provided - make sure you have set `id_request: yes` in the step"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// loadInlineSessionPolicy reads the inline session policy from its file when
+// configured, renders it with the build context, then validates and minifies it.
+func (c *Config) loadInlineSessionPolicy() error {
+	c.inlineSessionPolicy = ""
+
+	policy := c.AWS.InlineSessionPolicy
+
+	if c.AWS.InlineSessionPolicyFile != "" {
+		if policy != "" {
+			return fmt.Errorf("only one of inline_session_policy or inline_session_policy_file may be provided")
+		}
+
+		data, err := os.ReadFile(c.AWS.InlineSessionPolicyFile)
+		if err != nil {
+			return fmt.Errorf("unable to read inline session policy file: %w", err)
+		}
+
+		policy = string(data)
+	}
+
+	if policy == "" {
+		return nil
+	}
+
+	policy, err := c.renderDocumentTemplate("inline session policy", policy)
+	if err != nil {
+		return err
+	}
+
+	policy, err = NormalizeSessionPolicy(policy)
+	if err != nil {
+		return err
+	}
+
+	c.inlineSessionPolicy = policy
+
 	return nil
 }
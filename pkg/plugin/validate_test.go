@@ -17,7 +17,8 @@ func TestPlugin_Validate(t *testing.T) {
 			name: "all fields are populated",
 			config: &Config{
 				AWS: &AWS{
-					Role:                "testRole",
+					Region:              "us-east-1",
+					Role:                "arn:aws:iam::123456123456:role/test",
 					RoleDurationSeconds: 3600,
 				},
 				//nolint:gosec // ignore false positive for hardcoded credential
@@ -34,7 +35,8 @@ func TestPlugin_Validate(t *testing.T) {
 			name: "unsupported script format",
 			config: &Config{
 				AWS: &AWS{
-					Role:                "testRole",
+					Region:              "us-east-1",
+					Role:                "arn:aws:iam::123456123456:role/test",
 					RoleDurationSeconds: 3600,
 				},
 				//nolint:gosec // ignore false positive for hardcoded credential
@@ -51,6 +53,7 @@ func TestPlugin_Validate(t *testing.T) {
 			name: "AWS Role field is empty",
 			config: &Config{
 				AWS: &AWS{
+					Region:              "us-east-1",
 					Role:                "",
 					RoleDurationSeconds: 3600,
 				},
@@ -68,7 +71,8 @@ func TestPlugin_Validate(t *testing.T) {
 			name: "AWS RoleDurationSeconds field is 0",
 			config: &Config{
 				AWS: &AWS{
-					Role:                "testRole",
+					Region:              "us-east-1",
+					Role:                "arn:aws:iam::123456123456:role/test",
 					RoleDurationSeconds: 0,
 				},
 				Vela: &Vela{
@@ -83,7 +87,8 @@ func TestPlugin_Validate(t *testing.T) {
 			name: "Vela RequestTokenURL field is empty",
 			config: &Config{
 				AWS: &AWS{
-					Role:                "testRole",
+					Region:              "us-east-1",
+					Role:                "arn:aws:iam::123456123456:role/test",
 					RoleDurationSeconds: 3600,
 				},
 				Vela: &Vela{
@@ -98,7 +103,8 @@ func TestPlugin_Validate(t *testing.T) {
 			name: "Vela RequestToken field is empty",
 			config: &Config{
 				AWS: &AWS{
-					Role:                "testRole",
+					Region:              "us-east-1",
+					Role:                "arn:aws:iam::123456123456:role/test",
 					RoleDurationSeconds: 3600,
 				},
 				//nolint:gosec // ignore false positive for hardcoded credential
@@ -111,6 +117,134 @@ func TestPlugin_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "AWS Role is not a valid role ARN",
+			config: &Config{
+				AWS: &AWS{
+					Region:              "us-east-1",
+					Role:                "arn:aws:iam::123456123456:user/test",
+					RoleDurationSeconds: 3600,
+				},
+				//nolint:gosec // ignore false positive for hardcoded credential
+				Vela: &Vela{
+					RequestToken:    "testToken",
+					RequestTokenURL: "http://127.0.0.1",
+				},
+				Logger:       logrus.NewEntry(logrus.StandardLogger()),
+				ScriptFormat: ScriptFormatShell,
+			},
+			wantErr: true,
+		},
+		{
+			name: "AWS Role with path in GovCloud",
+			config: &Config{
+				AWS: &AWS{
+					Region:              "us-gov-west-1",
+					Role:                "arn:aws-us-gov:iam::123456123456:role/service/deploy",
+					RoleDurationSeconds: 3600,
+				},
+				//nolint:gosec // ignore false positive for hardcoded credential
+				Vela: &Vela{
+					RequestToken:    "testToken",
+					RequestTokenURL: "http://127.0.0.1",
+				},
+				Logger:       logrus.NewEntry(logrus.StandardLogger()),
+				ScriptFormat: ScriptFormatShell,
+			},
+			wantErr: false,
+		},
+		{
+			name: "AWS Region is not in the role partition",
+			config: &Config{
+				AWS: &AWS{
+					Region:              "cn-north-1",
+					Role:                "arn:aws:iam::123456123456:role/test",
+					RoleDurationSeconds: 3600,
+				},
+				//nolint:gosec // ignore false positive for hardcoded credential
+				Vela: &Vela{
+					RequestToken:    "testToken",
+					RequestTokenURL: "http://127.0.0.1",
+				},
+				Logger:       logrus.NewEntry(logrus.StandardLogger()),
+				ScriptFormat: ScriptFormatShell,
+			},
+			wantErr: true,
+		},
+		{
+			name: "AWS Region is unknown",
+			config: &Config{
+				AWS: &AWS{
+					Region:              "us-future-1",
+					Role:                "arn:aws:iam::123456123456:role/test",
+					RoleDurationSeconds: 3600,
+				},
+				//nolint:gosec // ignore false positive for hardcoded credential
+				Vela: &Vela{
+					RequestToken:    "testToken",
+					RequestTokenURL: "http://127.0.0.1",
+				},
+				Logger:       logrus.NewEntry(logrus.StandardLogger()),
+				ScriptFormat: ScriptFormatShell,
+			},
+			wantErr: true,
+		},
+		{
+			name: "AWS Region is an additional region",
+			config: &Config{
+				AWS: &AWS{
+					Region:              "us-future-1",
+					AdditionalRegions:   []string{"us-future-1"},
+					Role:                "arn:aws:iam::123456123456:role/test",
+					RoleDurationSeconds: 3600,
+				},
+				//nolint:gosec // ignore false positive for hardcoded credential
+				Vela: &Vela{
+					RequestToken:    "testToken",
+					RequestTokenURL: "http://127.0.0.1",
+				},
+				Logger:       logrus.NewEntry(logrus.StandardLogger()),
+				ScriptFormat: ScriptFormatShell,
+			},
+			wantErr: false,
+		},
+		{
+			name: "AWS RoleDurationSeconds field is out of bounds",
+			config: &Config{
+				AWS: &AWS{
+					Region:              "us-east-1",
+					Role:                "arn:aws:iam::123456123456:role/test",
+					RoleDurationSeconds: 86400,
+				},
+				//nolint:gosec // ignore false positive for hardcoded credential
+				Vela: &Vela{
+					RequestToken:    "testToken",
+					RequestTokenURL: "http://127.0.0.1",
+				},
+				Logger:       logrus.NewEntry(logrus.StandardLogger()),
+				ScriptFormat: ScriptFormatShell,
+			},
+			wantErr: true,
+		},
+		{
+			name: "AWS ManagedSessionPolicies field contains an invalid ARN",
+			config: &Config{
+				AWS: &AWS{
+					Region:                 "us-east-1",
+					Role:                   "arn:aws:iam::123456123456:role/test",
+					RoleDurationSeconds:    3600,
+					ManagedSessionPolicies: []string{"arn:aws:iam::aws:policy/ReadOnlyAccess", "ReadOnlyAccess"},
+				},
+				//nolint:gosec // ignore false positive for hardcoded credential
+				Vela: &Vela{
+					RequestToken:    "testToken",
+					RequestTokenURL: "http://127.0.0.1",
+				},
+				Logger:       logrus.NewEntry(logrus.StandardLogger()),
+				ScriptFormat: ScriptFormatShell,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -124,3 +258,89 @@ func TestPlugin_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestPlugin_Validate_AggregatesErrors(t *testing.T) {
+	c := &Config{
+		AWS: &AWS{
+			Region:                 "us-east-1",
+			Role:                   "testRole",
+			RoleDurationSeconds:    60,
+			ManagedSessionPolicies: []string{"ReadOnlyAccess"},
+		},
+		Vela:         &Vela{},
+		Logger:       logrus.NewEntry(logrus.StandardLogger()),
+		ScriptFormat: ScriptFormatShell,
+	}
+
+	err := c.Validate()
+	assert.Error(t, err, "An error was expected")
+
+	for _, want := range []string{
+		`role "testRole" is not a valid IAM role ARN`,
+		"role duration 60 must be between 900 and 43200 seconds",
+		`managed session policy "ReadOnlyAccess" is not a valid IAM policy ARN`,
+		"no request token url provided",
+		"no request token provided",
+	} {
+		assert.ErrorContains(t, err, want)
+	}
+}
+
+func TestPlugin_Validate_InlineSessionPolicyFile(t *testing.T) {
+	c := &Config{
+		AWS: &AWS{
+			Region:                  "us-east-1",
+			Role:                    "arn:aws:iam::123456123456:role/test",
+			RoleDurationSeconds:     3600,
+			InlineSessionPolicyFile: "testdata/session_policy.yml",
+		},
+		//nolint:gosec // ignore false positive for hardcoded credential
+		Vela: &Vela{
+			RequestToken:    "testToken",
+			RequestTokenURL: "http://127.0.0.1",
+		},
+		Logger:       logrus.NewEntry(logrus.StandardLogger()),
+		ScriptFormat: ScriptFormatShell,
+	}
+
+	err := c.Validate()
+	assert.NoError(t, err, "No error was expected")
+	assert.Equal(t,
+		`{"Statement":[{"Action":["s3:GetObject","s3:ListBucket"],"Effect":"Allow","Resource":["arn:aws:s3:::artifacts","arn:aws:s3:::artifacts/*"],"Sid":"ReadArtifacts"}],"Version":"2012-10-17"}`,
+		c.inlineSessionPolicy)
+
+	// validating again reads the file again rather than the loaded policy
+	assert.NoError(t, c.Validate())
+	assert.Empty(t, c.AWS.InlineSessionPolicy)
+	assert.Contains(t, c.inlineSessionPolicy, "ReadArtifacts")
+
+	c.AWS.InlineSessionPolicy = `{"Version":"2012-10-17","Statement":[]}`
+
+	err = c.Validate()
+	assert.ErrorContains(t, err, "only one of inline_session_policy or inline_session_policy_file may be provided")
+}
+
+func TestPlugin_Validate_Cleanup(t *testing.T) {
+	c := &Config{
+		Action:       ActionCleanup,
+		ManifestPath: "/vela/secrets/aws/manifest.json",
+		AWS:          &AWS{},
+		Build:        &BuildContext{},
+		Vela:         &Vela{},
+		Logger:       logrus.NewEntry(logrus.StandardLogger()),
+	}
+
+	// the role, duration and request token are not needed to delete files
+	assert.NoError(t, c.Validate())
+
+	c.AWS.RevokeRole = "arn:aws:iam::123456123456:role/revoke"
+
+	err := c.Validate()
+	assert.ErrorContains(t, err, "no role provided")
+	assert.ErrorContains(t, err, "no request token url provided")
+	assert.ErrorContains(t, err, "no request token provided")
+
+	c.Action = "delete"
+
+	assert.ErrorContains(t, c.Validate(), "only actions of [credentials cleanup] are supported")
+}
@@ -1,6 +1,8 @@
 package plugin
 
 import (
+	"time"
+
 	"github.com/urfave/cli/v2"
 )
 
@@ -9,6 +11,33 @@ var (
 	Flags = []cli.Flag{
 		// Plugin Configuration Flags
 
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_ACTION", "AWS_CREDENTIALS_ACTION"},
+			FilePath: "/vela/parameters/aws-credentials/action,/vela/secrets/aws-credentials/action",
+			Name:     FlagAction,
+			Usage:    "action to run - credentials issues AWS credentials, cleanup deletes the files written by earlier steps",
+			Value:    ActionCredentials,
+		},
+		// the allowlist is only read from an admin secret so pipelines cannot relax it with a parameter
+		&cli.StringFlag{
+			EnvVars:  []string{"AWS_CREDENTIALS_ALLOWLIST"},
+			FilePath: "/vela/secrets/aws-credentials/allowlist",
+			Name:     FlagAllowlist,
+			Usage:    "YAML or JSON allowlist of the roles each org, repo, branch and event may assume",
+		},
+		// the audit sinks are only read from admin secrets so pipelines cannot redirect or disable them
+		&cli.StringFlag{
+			EnvVars:  []string{"AWS_CREDENTIALS_AUDIT_FILE"},
+			FilePath: "/vela/secrets/aws-credentials/audit_file",
+			Name:     FlagAuditFile,
+			Usage:    "path of a file to append a JSON audit event to for every credential issuance",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"AWS_CREDENTIALS_AUDIT_WEBHOOK"},
+			FilePath: "/vela/secrets/aws-credentials/audit_webhook",
+			Name:     FlagAuditWebhook,
+			Usage:    "URL to POST a JSON audit event to for every credential issuance",
+		},
 		&cli.StringFlag{
 			EnvVars:  []string{"PARAMETER_AUDIENCE", "AWS_CREDENTIALS_AUDIENCE"},
 			FilePath: "/vela/parameters/aws-credentials/audience,/vela/secrets/aws-credentials/audience",
@@ -16,6 +45,34 @@ var (
 			Usage:    "Audience to use for the OIDC provider",
 			Value:    "sts.amazonaws.com",
 		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_CONFIG_FILE", "AWS_CREDENTIALS_CONFIG_FILE"},
+			FilePath: "/vela/parameters/aws-credentials/config_file,/vela/secrets/aws-credentials/config_file",
+			Name:     FlagConfigFile,
+			Usage:    "path of a YAML or JSON file of parameters, overridden by the parameters set in the step",
+		},
+		&cli.BoolFlag{
+			EnvVars: []string{"PARAMETER_DRY_RUN", "AWS_CREDENTIALS_DRY_RUN"},
+			Name:    FlagDryRun,
+			Usage:   "if the role assumption should be validated and explained without calling AWS",
+		},
+		&cli.BoolFlag{
+			EnvVars: []string{"PARAMETER_DRY_RUN_TOKEN", "AWS_CREDENTIALS_DRY_RUN_TOKEN"},
+			Name:    FlagDryRunToken,
+			Usage:   "if the ID token should be requested and decoded, but not exchanged, during a dry run",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_ISSUER", "AWS_CREDENTIALS_ISSUER"},
+			FilePath: "/vela/parameters/aws-credentials/issuer,/vela/secrets/aws-credentials/issuer",
+			Name:     FlagIssuer,
+			Usage:    "expected issuer of the ID token (defaults to https://<vela server>/_services/token)",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_JWKS_URL", "AWS_CREDENTIALS_JWKS_URL"},
+			FilePath: "/vela/parameters/aws-credentials/jwks_url,/vela/secrets/aws-credentials/jwks_url",
+			Name:     FlagJWKSURL,
+			Usage:    "URL of the issuer's JWKS (defaults to the jwks_uri from the issuer's discovery document)",
+		},
 		&cli.StringFlag{
 			EnvVars:  []string{"PARAMETER_LOG_FORMAT", "AWS_CREDENTIALS_LOG_FORMAT"},
 			FilePath: "/vela/parameters/aws-credentials/log_format,/vela/secrets/aws-credentials/log_format",
@@ -30,12 +87,95 @@ var (
 			Usage:    "set log level - options: (trace|debug|info|warn|error|fatal|panic)",
 			Value:    "info",
 		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_MANIFEST_PATH", "AWS_CREDENTIALS_MANIFEST_PATH"},
+			FilePath: "/vela/parameters/aws-credentials/manifest_path,/vela/secrets/aws-credentials/manifest_path",
+			Name:     FlagManifestPath,
+			Usage:    "path of the manifest recording the files written by the plugin for the cleanup action",
+			Value:    "/vela/secrets/aws/manifest.json",
+		},
+		&cli.BoolFlag{
+			EnvVars: []string{"PARAMETER_OUTPUTS", "AWS_CREDENTIALS_OUTPUTS"},
+			Name:    FlagOutputs,
+			Usage:   "if the session details should be written to the Vela outputs file",
+		},
+		&cli.BoolFlag{
+			EnvVars: []string{"PARAMETER_MASKED_OUTPUTS", "AWS_CREDENTIALS_MASKED_OUTPUTS"},
+			Name:    FlagOutputsMasked,
+			Usage:   "if the AWS credentials should be written to the Vela masked outputs file",
+		},
 		&cli.StringFlag{
 			EnvVars:  []string{"PARAMETER_SCRIPT_PATH", "AWS_CREDENTIALS_SCRIPT_PATH"},
 			FilePath: "/vela/parameters/aws-credentials/script_path,/vela/secrets/aws-credentials/script_path",
 			Name:     FlagScriptPath,
 			Usage:    "path where to write script that contains AWS credentials",
 		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_SECRETS", "AWS_CREDENTIALS_SECRETS"},
+			FilePath: "/vela/parameters/aws-credentials/secrets,/vela/secrets/aws-credentials/secrets",
+			Name:     FlagSecrets,
+			Usage:    "Secrets Manager secrets and SSM parameters (JSON or YAML) to fetch with the AWS credentials",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_SECRETS_ENV_FILE", "AWS_CREDENTIALS_SECRETS_ENV_FILE"},
+			FilePath: "/vela/parameters/aws-credentials/secrets_env_file,/vela/secrets/aws-credentials/secrets_env_file",
+			Name:     FlagSecretsEnvFile,
+			Usage:    "path of the env file to write the fetched secrets without a file to",
+			Value:    "/vela/secrets/aws/secrets.env",
+		},
+		&cli.StringSliceFlag{
+			EnvVars:  []string{"PARAMETER_ECR_REGISTRIES", "AWS_CREDENTIALS_ECR_REGISTRIES"},
+			FilePath: "/vela/parameters/aws-credentials/ecr_registries,/vela/secrets/aws-credentials/ecr_registries",
+			Name:     FlagECRRegistries,
+			Usage:    "ECR registries (account IDs or registry hostnames) to log in to with the AWS credentials",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_DOCKER_CONFIG", "AWS_CREDENTIALS_DOCKER_CONFIG"},
+			FilePath: "/vela/parameters/aws-credentials/docker_config,/vela/secrets/aws-credentials/docker_config",
+			Name:     FlagDockerConfig,
+			Usage:    "path of the Docker config file to write the ECR logins to",
+			Value:    "/vela/secrets/aws/docker/config.json",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_CODEARTIFACT", "AWS_CREDENTIALS_CODEARTIFACT"},
+			FilePath: "/vela/parameters/aws-credentials/codeartifact,/vela/secrets/aws-credentials/codeartifact",
+			Name:     FlagCodeArtifact,
+			Usage:    "CodeArtifact domain and repositories (JSON or YAML) to configure the package managers for with the AWS credentials",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_CODEARTIFACT_DIR", "AWS_CREDENTIALS_CODEARTIFACT_DIR"},
+			FilePath: "/vela/parameters/aws-credentials/codeartifact_dir,/vela/secrets/aws-credentials/codeartifact_dir",
+			Name:     FlagCodeArtifactDir,
+			Usage:    "directory to write the CodeArtifact package manager configuration files to",
+			Value:    "/vela/secrets/aws/codeartifact",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_EKS_CLUSTERS", "AWS_CREDENTIALS_EKS_CLUSTERS"},
+			FilePath: "/vela/parameters/aws-credentials/eks_clusters,/vela/secrets/aws-credentials/eks_clusters",
+			Name:     FlagEKSClusters,
+			Usage:    "EKS clusters (JSON or YAML) to write to the kubeconfig",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_KUBECONFIG", "AWS_CREDENTIALS_KUBECONFIG"},
+			FilePath: "/vela/parameters/aws-credentials/kubeconfig,/vela/secrets/aws-credentials/kubeconfig",
+			Name:     FlagKubeconfig,
+			Usage:    "path of the kubeconfig to write the EKS clusters to",
+			Value:    "/vela/secrets/aws/kubeconfig",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_KUBECONFIG_AUTH", "AWS_CREDENTIALS_KUBECONFIG_AUTH"},
+			FilePath: "/vela/parameters/aws-credentials/kubeconfig_auth,/vela/secrets/aws-credentials/kubeconfig_auth",
+			Name:     FlagKubeconfigAuth,
+			Usage:    "how the kubeconfig users authenticate to the EKS clusters (token or exec)",
+			Value:    KubeconfigAuthToken,
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_KUBECONFIG_EXEC_COMMAND", "AWS_CREDENTIALS_KUBECONFIG_EXEC_COMMAND"},
+			FilePath: "/vela/parameters/aws-credentials/kubeconfig_exec_command,/vela/secrets/aws-credentials/kubeconfig_exec_command",
+			Name:     FlagKubeconfigExecCommand,
+			Usage:    "command of this plugin run by the kubeconfig exec plugin",
+			Value:    "vela-aws-credentials",
+		},
 		&cli.StringFlag{
 			EnvVars:  []string{"PARAMETER_SCRIPT_FORMAT", "AWS_CREDENTIALS_SCRIPT_FORMAT"},
 			FilePath: "/vela/parameters/aws-credentials/script_format,/vela/secrets/aws-credentials/script_format",
@@ -49,19 +189,135 @@ var (
 			Usage:   "if the credentials script should be created",
 			Value:   false,
 		},
+		&cli.DurationFlag{
+			EnvVars:  []string{"PARAMETER_TIMEOUT", "AWS_CREDENTIALS_TIMEOUT"},
+			FilePath: "/vela/parameters/aws-credentials/timeout,/vela/secrets/aws-credentials/timeout",
+			Name:     FlagTimeout,
+			Usage:    "overall timeout for the plugin (0 disables the timeout)",
+			Value:    5 * time.Minute,
+		},
+		&cli.DurationFlag{
+			EnvVars:  []string{"PARAMETER_TOKEN_TIMEOUT", "AWS_CREDENTIALS_TOKEN_TIMEOUT"},
+			FilePath: "/vela/parameters/aws-credentials/token_timeout,/vela/secrets/aws-credentials/token_timeout",
+			Name:     FlagTimeoutToken,
+			Usage:    "timeout for requesting (and verifying) the ID token, including retries (0 disables the timeout)",
+			Value:    time.Minute,
+		},
+		&cli.DurationFlag{
+			EnvVars:  []string{"PARAMETER_ASSUME_ROLE_TIMEOUT", "AWS_CREDENTIALS_ASSUME_ROLE_TIMEOUT"},
+			FilePath: "/vela/parameters/aws-credentials/assume_role_timeout,/vela/secrets/aws-credentials/assume_role_timeout",
+			Name:     FlagTimeoutAssumeRole,
+			Usage:    "timeout for assuming the AWS IAM role, including retries (0 disables the timeout)",
+			Value:    time.Minute,
+		},
+		&cli.DurationFlag{
+			EnvVars:  []string{"PARAMETER_VERIFY_TIMEOUT", "AWS_CREDENTIALS_VERIFY_TIMEOUT"},
+			FilePath: "/vela/parameters/aws-credentials/verify_timeout,/vela/secrets/aws-credentials/verify_timeout",
+			Name:     FlagTimeoutVerify,
+			Usage:    "timeout for verifying the AWS credentials (0 disables the timeout)",
+			Value:    30 * time.Second,
+		},
+		&cli.DurationFlag{
+			EnvVars:  []string{"PARAMETER_SECRETS_TIMEOUT", "AWS_CREDENTIALS_SECRETS_TIMEOUT"},
+			FilePath: "/vela/parameters/aws-credentials/secrets_timeout,/vela/secrets/aws-credentials/secrets_timeout",
+			Name:     FlagTimeoutSecrets,
+			Usage:    "timeout for fetching the secrets (0 disables the timeout)",
+			Value:    time.Minute,
+		},
+		&cli.DurationFlag{
+			EnvVars:  []string{"PARAMETER_ECR_TIMEOUT", "AWS_CREDENTIALS_ECR_TIMEOUT"},
+			FilePath: "/vela/parameters/aws-credentials/ecr_timeout,/vela/secrets/aws-credentials/ecr_timeout",
+			Name:     FlagTimeoutECR,
+			Usage:    "timeout for logging in to the ECR registries (0 disables the timeout)",
+			Value:    time.Minute,
+		},
+		&cli.DurationFlag{
+			EnvVars:  []string{"PARAMETER_CODEARTIFACT_TIMEOUT", "AWS_CREDENTIALS_CODEARTIFACT_TIMEOUT"},
+			FilePath: "/vela/parameters/aws-credentials/codeartifact_timeout,/vela/secrets/aws-credentials/codeartifact_timeout",
+			Name:     FlagTimeoutCodeArtifact,
+			Usage:    "timeout for logging in to CodeArtifact (0 disables the timeout)",
+			Value:    time.Minute,
+		},
+		&cli.DurationFlag{
+			EnvVars:  []string{"PARAMETER_KUBECONFIG_TIMEOUT", "AWS_CREDENTIALS_KUBECONFIG_TIMEOUT"},
+			FilePath: "/vela/parameters/aws-credentials/kubeconfig_timeout,/vela/secrets/aws-credentials/kubeconfig_timeout",
+			Name:     FlagTimeoutKubeconfig,
+			Usage:    "timeout for describing the EKS clusters and writing the kubeconfig (0 disables the timeout)",
+			Value:    time.Minute,
+		},
+		&cli.DurationFlag{
+			EnvVars:  []string{"PARAMETER_REVOKE_TIMEOUT", "AWS_CREDENTIALS_REVOKE_TIMEOUT"},
+			FilePath: "/vela/parameters/aws-credentials/revoke_timeout,/vela/secrets/aws-credentials/revoke_timeout",
+			Name:     FlagTimeoutRevoke,
+			Usage:    "timeout for attaching the revoke policies during cleanup, including retries (0 disables the timeout)",
+			Value:    time.Minute,
+		},
 		&cli.BoolFlag{
 			EnvVars: []string{"PARAMETER_VERIFY", "AWS_CREDENTIALS_VERIFY"},
 			Name:    FlagVerify,
 			Usage:   "if the AWS credentials should be validated",
 		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_VERIFY_ACCOUNT_ID", "AWS_CREDENTIALS_VERIFY_ACCOUNT_ID"},
+			FilePath: "/vela/parameters/aws-credentials/verify_account_id,/vela/secrets/aws-credentials/verify_account_id",
+			Name:     FlagVerifyAccountID,
+			Usage:    "account ID the AWS credentials are expected to belong to (defaults to the account of the role)",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_VERIFY_ASSUMED_ROLE_ARN", "AWS_CREDENTIALS_VERIFY_ASSUMED_ROLE_ARN"},
+			FilePath: "/vela/parameters/aws-credentials/verify_assumed_role_arn,/vela/secrets/aws-credentials/verify_assumed_role_arn",
+			Name:     FlagVerifyAssumedRoleARN,
+			Usage:    "assumed role ARN the AWS credentials are expected to belong to (defaults to the role and session name)",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_VERIFY_PROBES", "AWS_CREDENTIALS_VERIFY_PROBES"},
+			FilePath: "/vela/parameters/aws-credentials/verify_probes,/vela/secrets/aws-credentials/verify_probes",
+			Name:     FlagVerifyProbes,
+			Usage:    "checks (JSON or YAML) to run with the AWS credentials when verify is enabled",
+		},
+		&cli.BoolFlag{
+			EnvVars: []string{"PARAMETER_VERIFY_TOKEN", "AWS_CREDENTIALS_VERIFY_TOKEN"},
+			Name:    FlagVerifyToken,
+			Usage:   "if the ID token signature and issuer should be verified against the issuer's JWKS",
+		},
 
 		// AWS Configuration Flags
 
+		&cli.StringSliceFlag{
+			EnvVars:  []string{"PARAMETER_ADDITIONAL_REGIONS", "AWS_CREDENTIALS_ADDITIONAL_REGIONS"},
+			FilePath: "/vela/parameters/aws-credentials/additional_regions,/vela/secrets/aws-credentials/additional_regions",
+			Name:     FlagAWSAdditionalRegions,
+			Usage:    "regions to accept in addition to the known AWS regions, for regions launched after this plugin was released",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_AWS_CONFIG_FILE", "AWS_CREDENTIALS_AWS_CONFIG_FILE"},
+			FilePath: "/vela/parameters/aws-credentials/aws_config_file,/vela/secrets/aws-credentials/aws_config_file",
+			Name:     FlagAWSConfigFile,
+			Usage:    "path of the AWS shared config file to read the profile from (defaults to ~/.aws/config)",
+		},
+		&cli.BoolFlag{
+			EnvVars: []string{"PARAMETER_DURATION_FALLBACK", "AWS_CREDENTIALS_DURATION_FALLBACK"},
+			Name:    FlagAWSDurationFallback,
+			Usage:   "if the role should be assumed with a shorter duration when the role's maximum session duration is exceeded",
+		},
+		&cli.IntSliceFlag{
+			EnvVars:  []string{"PARAMETER_DURATION_LADDER", "AWS_CREDENTIALS_DURATION_LADDER"},
+			FilePath: "/vela/parameters/aws-credentials/duration_ladder,/vela/secrets/aws-credentials/duration_ladder",
+			Name:     FlagAWSDurationLadder,
+			Usage:    "durations in seconds to fall back to, in order, when duration_fallback is enabled",
+			Value:    cli.NewIntSlice(43200, 21600, 14400, 10800, 7200, 3600, 900),
+		},
 		&cli.StringFlag{
 			EnvVars:  []string{"PARAMETER_INLINE_SESSION_POLICY", "AWS_CREDENTIALS_INLINE_SESSION_POLICY"},
 			FilePath: "/vela/parameters/aws-credentials/inline_session_policy,/vela/secrets/aws-credentials/inline_session_policy",
 			Name:     FlagAWSInlineSessionPolicy,
-			Usage:    "Inline session policy to use when assuming the role",
+			Usage:    "Inline session policy (JSON or YAML) to use when assuming the role",
+		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_INLINE_SESSION_POLICY_FILE", "AWS_CREDENTIALS_INLINE_SESSION_POLICY_FILE"},
+			FilePath: "/vela/parameters/aws-credentials/inline_session_policy_file,/vela/secrets/aws-credentials/inline_session_policy_file",
+			Name:     FlagAWSInlineSessionPolicyFile,
+			Usage:    "path to a file containing the inline session policy (JSON or YAML) to use when assuming the role",
 		},
 		&cli.StringSliceFlag{
 			EnvVars:  []string{"PARAMETER_MANAGED_SESSION_POLICIES", "AWS_CREDENTIALS_MANAGED_SESSION_POLICIES"},
@@ -69,6 +325,12 @@ var (
 			Name:     FlagAWSManagedSessionPolicies,
 			Usage:    "list of managed session policies to use when assuming the role",
 		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_PROFILE", "AWS_CREDENTIALS_PROFILE"},
+			FilePath: "/vela/parameters/aws-credentials/profile,/vela/secrets/aws-credentials/profile",
+			Name:     FlagAWSProfile,
+			Usage:    "profile of the AWS shared config file whose role_arn and source_profile chain to assume",
+		},
 		&cli.StringFlag{
 			EnvVars:  []string{"PARAMETER_REGION", "AWS_CREDENTIALS_REGION"},
 			FilePath: "/vela/parameters/aws-credentials/region,/vela/secrets/aws-credentials/region",
@@ -82,6 +344,12 @@ var (
 			Name:     FlagAWSRole,
 			Usage:    "AWS IAM role to assume",
 		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_REVOKE_ROLE", "AWS_CREDENTIALS_REVOKE_ROLE"},
+			FilePath: "/vela/parameters/aws-credentials/revoke_role,/vela/secrets/aws-credentials/revoke_role",
+			Name:     FlagAWSRevokeRole,
+			Usage:    "AWS IAM role assumed during cleanup to revoke the sessions issued for the build's roles",
+		},
 		&cli.IntFlag{
 			EnvVars:  []string{"PARAMETER_ROLE_DURATION_SECONDS", "AWS_CREDENTIALS_ROLE_DURATION_SECONDS"},
 			FilePath: "/vela/parameters/aws-credentials/role_duration_seconds,/vela/secrets/aws-credentials/role_duration_seconds",
@@ -89,6 +357,12 @@ var (
 			Usage:    "Role duration in seconds",
 			Value:    3600,
 		},
+		&cli.StringFlag{
+			EnvVars:  []string{"PARAMETER_ROLE_RULES", "AWS_CREDENTIALS_ROLE_RULES"},
+			FilePath: "/vela/parameters/aws-credentials/role_rules,/vela/secrets/aws-credentials/role_rules",
+			Name:     FlagAWSRoleRules,
+			Usage:    "ordered rules (JSON or YAML) selecting the role, region and duration by branch, event, tag and deployment target",
+		},
 		&cli.StringFlag{
 			EnvVars:  []string{"PARAMETER_ROLE_SESSION_NAME", "AWS_CREDENTIALS_ROLE_SESSION_NAME"},
 			FilePath: "/vela/parameters/aws-credentials/role_session_name,/vela/secrets/aws-credentials/role_session_name",
@@ -97,13 +371,79 @@ var (
 			Value:    "vela",
 		},
 
+		// Retry Configuration Flags
+
+		&cli.IntFlag{
+			EnvVars:  []string{"PARAMETER_RETRY_ATTEMPTS", "AWS_CREDENTIALS_RETRY_ATTEMPTS"},
+			FilePath: "/vela/parameters/aws-credentials/retry_attempts,/vela/secrets/aws-credentials/retry_attempts",
+			Name:     FlagRetryAttempts,
+			Usage:    "number of attempts for each Vela and STS API call",
+			Value:    3,
+		},
+		&cli.DurationFlag{
+			EnvVars:  []string{"PARAMETER_RETRY_BASE_DELAY", "AWS_CREDENTIALS_RETRY_BASE_DELAY"},
+			FilePath: "/vela/parameters/aws-credentials/retry_base_delay,/vela/secrets/aws-credentials/retry_base_delay",
+			Name:     FlagRetryBaseDelay,
+			Usage:    "delay before the first retry, doubled after each attempt",
+			Value:    time.Second,
+		},
+		&cli.DurationFlag{
+			EnvVars:  []string{"PARAMETER_RETRY_MAX_DELAY", "AWS_CREDENTIALS_RETRY_MAX_DELAY"},
+			FilePath: "/vela/parameters/aws-credentials/retry_max_delay,/vela/secrets/aws-credentials/retry_max_delay",
+			Name:     FlagRetryMaxDelay,
+			Usage:    "maximum delay between retries",
+			Value:    20 * time.Second,
+		},
+		&cli.Float64Flag{
+			EnvVars:  []string{"PARAMETER_RETRY_JITTER", "AWS_CREDENTIALS_RETRY_JITTER"},
+			FilePath: "/vela/parameters/aws-credentials/retry_jitter,/vela/secrets/aws-credentials/retry_jitter",
+			Name:     FlagRetryJitter,
+			Usage:    "fraction of each retry delay to randomize, between 0 and 1",
+			Value:    0.5,
+		},
+
 		// Vela Configuration Flags
 
+		&cli.StringFlag{
+			EnvVars: []string{"VELA_BUILD_AUTHOR", "BUILD_AUTHOR"},
+			Name:    FlagVelaBuildAuthor,
+			Usage:   "environment variable reference for reading in build author",
+		},
+		&cli.StringFlag{
+			EnvVars: []string{"VELA_BUILD_BRANCH", "BUILD_BRANCH"},
+			Name:    FlagVelaBuildBranch,
+			Usage:   "environment variable reference for reading in build branch",
+		},
+		&cli.StringFlag{
+			EnvVars: []string{"VELA_BUILD_COMMIT", "BUILD_COMMIT"},
+			Name:    FlagVelaBuildCommit,
+			Usage:   "environment variable reference for reading in build commit SHA",
+		},
+		&cli.StringFlag{
+			EnvVars: []string{"VELA_BUILD_EVENT", "BUILD_EVENT"},
+			Name:    FlagVelaBuildEvent,
+			Usage:   "environment variable reference for reading in build event",
+		},
+		&cli.StringFlag{
+			EnvVars: []string{"VELA_BUILD_LINK", "BUILD_LINK"},
+			Name:    FlagVelaBuildLink,
+			Usage:   "environment variable reference for reading in build link",
+		},
 		&cli.IntFlag{
 			EnvVars: []string{"VELA_BUILD_NUMBER", "BUILD_NUMBER"},
 			Name:    FlagVelaBuildNumber,
 			Usage:   "environment variable reference for reading in build number",
 		},
+		&cli.StringFlag{
+			EnvVars: []string{"VELA_BUILD_TAG", "BUILD_TAG"},
+			Name:    FlagVelaBuildTag,
+			Usage:   "environment variable reference for reading in build tag",
+		},
+		&cli.StringFlag{
+			EnvVars: []string{"VELA_DEPLOYMENT", "DEPLOYMENT"},
+			Name:    FlagVelaDeployment,
+			Usage:   "environment variable reference for reading in deployment target",
+		},
 		&cli.StringFlag{
 			EnvVars: []string{"VELA_ID_TOKEN_REQUEST_TOKEN"},
 			Name:    FlagVelaIDTokenRequestToken,
@@ -114,6 +454,16 @@ var (
 			Name:    FlagVelaIDTokenRequestURL,
 			Usage:   "environment variable reference for reading in OIDC request token URL",
 		},
+		&cli.StringFlag{
+			EnvVars: []string{"VELA_MASKED_OUTPUTS"},
+			Name:    FlagVelaMaskedOutputs,
+			Usage:   "environment variable reference for reading in masked outputs file path",
+		},
+		&cli.StringFlag{
+			EnvVars: []string{"VELA_OUTPUTS"},
+			Name:    FlagVelaOutputs,
+			Usage:   "environment variable reference for reading in outputs file path",
+		},
 		&cli.StringFlag{
 			EnvVars: []string{"VELA_REPO_ORG", "REPOSITORY_ORG"},
 			Name:    FlagVelaOrgName,
@@ -124,5 +474,10 @@ var (
 			Name:    FlagVelaRepoName,
 			Usage:   "environment variable reference for reading in repository name",
 		},
+		&cli.StringFlag{
+			EnvVars: []string{"VELA_BUILD_WORKSPACE", "VELA_WORKSPACE"},
+			Name:    FlagVelaWorkspace,
+			Usage:   "environment variable reference for reading in build workspace",
+		},
 	}
 )
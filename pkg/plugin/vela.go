@@ -5,12 +5,13 @@ package plugin
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 
 	"github.com/go-vela/sdk-go/vela"
 )
 
-func (c *Config) GenerateVelaToken() (string, error) {
+func (c *Config) GenerateVelaToken(ctx context.Context) (string, error) {
 	tokenURL, err := url.Parse(c.Vela.RequestTokenURL)
 	if err != nil {
 		return "", err
@@ -25,10 +26,25 @@ func (c *Config) GenerateVelaToken() (string, error) {
 
 	opt := &vela.IDTokenOptions{Audience: []string{c.Audience}}
 
-	token, _, err := client.Build.GetIDToken(context.Background(), c.Vela.OrgName, c.Vela.RepoName, c.Vela.BuildNumber, opt)
+	var token string
+
+	err = c.Retry.Do(ctx, c.Logger, "Vela GetIDToken", isRetryableVelaError, func(ctx context.Context) error {
+		t, resp, err := client.Build.GetIDToken(ctx, c.Build.Org, c.Build.Repo, c.Build.BuildNumber, opt)
+		if resp != nil && resp.StatusCode >= http.StatusMultipleChoices {
+			return &httpStatusError{StatusCode: resp.StatusCode, Err: err}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		token = t.GetToken()
+
+		return nil
+	})
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to get ID token: %w", err)
 	}
 
-	return token.GetToken(), nil
+	return token, nil
 }
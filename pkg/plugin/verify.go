@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"go.yaml.in/yaml/v3"
+)
+
+// Types of verification probes.
+const (
+	ProbeTypeSimulate                = "simulate"
+	ProbeTypeS3HeadBucket            = "s3_head_bucket"
+	ProbeTypeECRDescribeRepositories = "ecr_describe_repositories"
+)
+
+// VerifyProbe represents a check run with the assumed role credentials to
+// confirm they grant the permissions later steps rely on.
+type VerifyProbe struct {
+	Name         string   `yaml:"name"`
+	Type         string   `yaml:"type"`
+	Actions      []string `yaml:"actions"`
+	Resources    []string `yaml:"resources"`
+	Bucket       string   `yaml:"bucket"`
+	Repositories []string `yaml:"repositories"`
+	RegistryID   string   `yaml:"registry_id"`
+}
+
+// ParseVerifyProbes parses a YAML or JSON list of verification probes, rejecting unknown keys.
+func ParseVerifyProbes(data string) ([]VerifyProbe, error) {
+	var probes []VerifyProbe
+
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(&probes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse verify probes: %w", err)
+	}
+
+	for i, probe := range probes {
+		switch probe.Type {
+		case ProbeTypeSimulate:
+			if len(probe.Actions) == 0 {
+				return nil, fmt.Errorf("verify probe %s has no actions", probe.name(i))
+			}
+		case ProbeTypeS3HeadBucket:
+			if probe.Bucket == "" {
+				return nil, fmt.Errorf("verify probe %s has no bucket", probe.name(i))
+			}
+		case ProbeTypeECRDescribeRepositories:
+		default:
+			return nil, fmt.Errorf("verify probe %s has unsupported type %q (expected %s, %s or %s)",
+				probe.name(i), probe.Type, ProbeTypeSimulate, ProbeTypeS3HeadBucket, ProbeTypeECRDescribeRepositories)
+		}
+	}
+
+	return probes, nil
+}
+
+// name returns the name of the probe for messages, falling back to its index and type.
+func (p *VerifyProbe) name(i int) string {
+	if p.Name != "" {
+		return fmt.Sprintf("%q", p.Name)
+	}
+
+	return fmt.Sprintf("#%d (%s)", i, p.Type)
+}
+
+// VerifyCredentials confirms the assumed role credentials are usable by calling
+// GetCallerIdentity, checks the account and assumed role ARN they belong to,
+// then runs the verification probes.
+func (c *Config) VerifyCredentials(ctx context.Context, creds *aws.Credentials) error {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithCredentialsProvider(credentials.StaticCredentialsProvider{Value: *creds}), config.WithRegion(c.AWS.Region))
+	if err != nil {
+		return err
+	}
+
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return err
+	}
+
+	account, arn, err := c.expectedIdentity()
+	if err != nil {
+		return err
+	}
+
+	if got := aws.ToString(identity.Account); got != account {
+		return fmt.Errorf("credentials belong to account %s, expected %s", got, account)
+	}
+
+	if got := aws.ToString(identity.Arn); got != arn {
+		return fmt.Errorf("credentials belong to %s, expected %s", got, arn)
+	}
+
+	c.Logger.Infof("successfully validated credentials for %s", arn)
+
+	var errs []error
+
+	for i := range c.verifyProbes {
+		probe := &c.verifyProbes[i]
+
+		err := c.runProbe(ctx, cfg, probe)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("verify probe %s failed: %w", probe.name(i), err))
+
+			continue
+		}
+
+		c.Logger.Infof("verify probe %s passed", probe.name(i))
+	}
+
+	return errors.Join(errs...)
+}
+
+// expectedIdentity returns the account ID and assumed role ARN the credentials
+// should belong to, derived from the role ARN unless configured.
+func (c *Config) expectedIdentity() (string, string, error) {
+	role, err := ParseRoleARN(c.AWS.Role)
+	if err != nil {
+		return "", "", err
+	}
+
+	account := role.AccountID
+	if c.VerifyAccountID != "" {
+		account = c.VerifyAccountID
+	}
+
+	arn := fmt.Sprintf("arn:%s:sts::%s:assumed-role/%s/%s", role.Partition, role.AccountID, role.Name, c.AWS.RoleSessionName)
+	if c.VerifyAssumedRoleARN != "" {
+		arn = c.VerifyAssumedRoleARN
+	}
+
+	return account, arn, nil
+}
+
+// runProbe runs a single verification probe with the assumed role credentials.
+func (c *Config) runProbe(ctx context.Context, cfg aws.Config, probe *VerifyProbe) error {
+	switch probe.Type {
+	case ProbeTypeSimulate:
+		return c.simulateProbe(ctx, cfg, probe)
+	case ProbeTypeS3HeadBucket:
+		_, err := s3.NewFromConfig(cfg).HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(probe.Bucket)})
+
+		return err
+	case ProbeTypeECRDescribeRepositories:
+		input := &ecr.DescribeRepositoriesInput{RepositoryNames: probe.Repositories}
+		if probe.RegistryID != "" {
+			input.RegistryId = aws.String(probe.RegistryID)
+		}
+
+		_, err := ecr.NewFromConfig(cfg).DescribeRepositories(ctx, input)
+
+		return err
+	}
+
+	return fmt.Errorf("unsupported probe type %q", probe.Type)
+}
+
+// simulateProbe simulates the probe actions for the role with IAM, applying the
+// inline session policy as a boundary the way STS scopes down the session.
+// Managed session policies are not applied, since the simulation only accepts
+// a single boundary given as a document.
+func (c *Config) simulateProbe(ctx context.Context, cfg aws.Config, probe *VerifyProbe) error {
+	input := &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(c.AWS.Role),
+		ActionNames:     probe.Actions,
+		ResourceArns:    probe.Resources,
+	}
+
+	if c.inlineSessionPolicy != "" {
+		input.PermissionsBoundaryPolicyInputList = []string{c.inlineSessionPolicy}
+	}
+
+	var denied []string
+
+	paginator := iam.NewSimulatePrincipalPolicyPaginator(iam.NewFromConfig(cfg), input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range output.EvaluationResults {
+			if result.EvalDecision == "allowed" {
+				continue
+			}
+
+			resource := aws.ToString(result.EvalResourceName)
+			if resource == "" {
+				resource = "*"
+			}
+
+			denied = append(denied, fmt.Sprintf("%s on %s is %s", aws.ToString(result.EvalActionName), resource, result.EvalDecision))
+		}
+	}
+
+	if len(denied) > 0 {
+		return errors.New(strings.Join(denied, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// stsCallerIdentityResponse represents a GetCallerIdentity response from STS with the account and ARN.
+const stsCallerIdentityResponse = `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>%s</Arn>
+    <UserId>AROAEXAMPLE:vela</UserId>
+    <Account>%s</Account>
+  </GetCallerIdentityResult>
+</GetCallerIdentityResponse>`
+
+// iamSimulateResponse represents a SimulatePrincipalPolicy response from IAM with a single decision.
+const iamSimulateResponse = `<SimulatePrincipalPolicyResponse xmlns="https://iam.amazonaws.com/doc/2010-05-08/">
+  <SimulatePrincipalPolicyResult>
+    <IsTruncated>false</IsTruncated>
+    <EvaluationResults>
+      <member>
+        <EvalActionName>s3:PutObject</EvalActionName>
+        <EvalResourceName>arn:aws:s3:::artifacts/*</EvalResourceName>
+        <EvalDecision>%s</EvalDecision>
+      </member>
+    </EvaluationResults>
+  </SimulatePrincipalPolicyResult>
+</SimulatePrincipalPolicyResponse>`
+
+func TestConfig_VerifyCredentials(t *testing.T) {
+	tests := []struct {
+		name            string
+		account         string
+		arn             string
+		expectedAccount string
+		probes          string
+		decision        string
+		repository      bool
+		wantErr         string
+	}{
+		{
+			name:    "identity matches the role",
+			account: "123456123456",
+			arn:     "arn:aws:sts::123456123456:assumed-role/test/vela",
+		},
+		{
+			name:    "unexpected account",
+			account: "999999999999",
+			arn:     "arn:aws:sts::999999999999:assumed-role/test/vela",
+			wantErr: "credentials belong to account 999999999999, expected 123456123456",
+		},
+		{
+			name:    "unexpected role",
+			account: "123456123456",
+			arn:     "arn:aws:sts::123456123456:assumed-role/other/vela",
+			wantErr: "expected arn:aws:sts::123456123456:assumed-role/test/vela",
+		},
+		{
+			name:            "configured account",
+			account:         "999999999999",
+			arn:             "arn:aws:sts::123456123456:assumed-role/test/vela",
+			expectedAccount: "999999999999",
+		},
+		{
+			name:       "probes pass",
+			account:    "123456123456",
+			arn:        "arn:aws:sts::123456123456:assumed-role/test/vela",
+			probes:     `[{"name": "upload", "type": "simulate", "actions": ["s3:PutObject"], "resources": ["arn:aws:s3:::artifacts/*"]}, {"type": "ecr_describe_repositories", "repositories": ["app"]}]`,
+			decision:   "allowed",
+			repository: true,
+		},
+		{
+			name:     "simulated action denied",
+			account:  "123456123456",
+			arn:      "arn:aws:sts::123456123456:assumed-role/test/vela",
+			probes:   `[{"name": "upload", "type": "simulate", "actions": ["s3:PutObject"], "resources": ["arn:aws:s3:::artifacts/*"]}]`,
+			decision: "implicitDeny",
+			wantErr:  `verify probe "upload" failed: s3:PutObject on arn:aws:s3:::artifacts/* is implicitDeny`,
+		},
+		{
+			name:    "repository not found",
+			account: "123456123456",
+			arn:     "arn:aws:sts::123456123456:assumed-role/test/vela",
+			probes:  `[{"type": "ecr_describe_repositories", "repositories": ["app"]}]`,
+			wantErr: "verify probe #0 (ecr_describe_repositories) failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestAWS(t, func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.Header.Get("X-Amz-Target"), ".DescribeRepositories") {
+					w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+
+					if !tt.repository {
+						w.WriteHeader(http.StatusBadRequest)
+						fmt.Fprint(w, `{"__type": "RepositoryNotFoundException", "message": "The repository with name 'app' does not exist"}`)
+
+						return
+					}
+
+					fmt.Fprint(w, `{"repositories": [{"repositoryName": "app"}]}`)
+
+					return
+				}
+
+				_ = r.ParseForm()
+
+				w.Header().Set("Content-Type", "text/xml")
+
+				switch r.PostForm.Get("Action") {
+				case "GetCallerIdentity":
+					fmt.Fprintf(w, stsCallerIdentityResponse, tt.arn, tt.account)
+				case "SimulatePrincipalPolicy":
+					assert.Equal(t, "arn:aws:iam::123456123456:role/test", r.PostForm.Get("PolicySourceArn"))
+					fmt.Fprintf(w, iamSimulateResponse, tt.decision)
+				default:
+					t.Errorf("unexpected request %s", r.PostForm.Get("Action"))
+				}
+			})
+
+			c := &Config{
+				Verify:          true,
+				VerifyAccountID: tt.expectedAccount,
+				VerifyProbes:    tt.probes,
+				AWS: &AWS{
+					Region:          "us-east-1",
+					Role:            "arn:aws:iam::123456123456:role/test",
+					RoleSessionName: "vela",
+				},
+				Logger: logrus.NewEntry(logrus.StandardLogger()),
+			}
+
+			if tt.probes != "" {
+				probes, err := ParseVerifyProbes(tt.probes)
+				if err != nil {
+					t.Fatalf("unable to parse probes: %v", err)
+				}
+
+				c.verifyProbes = probes
+			}
+
+			err := c.VerifyCredentials(context.Background(), &aws.Credentials{
+				AccessKeyID:     "ACCESS_KEY_ID",
+				SecretAccessKey: "SECRET_ACCESS_KEY",
+				SessionToken:    "SESSION_TOKEN",
+			})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err, "No error was expected")
+		})
+	}
+}
+
+func TestPlugin_ParseVerifyProbes_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown type":    `[{"type": "lambda_invoke"}]`,
+		"unknown key":     `[{"type": "simulate", "actions": ["s3:GetObject"], "action": "typo"}]`,
+		"missing actions": `[{"type": "simulate"}]`,
+		"missing bucket":  `[{"type": "s3_head_bucket"}]`,
+	}
+
+	for name, probes := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseVerifyProbes(probes)
+			assert.Error(t, err, "An error was expected")
+		})
+	}
+}